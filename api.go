@@ -10,22 +10,26 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/fundex-id/bni-api-mgmt/config"
 	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
 	"github.com/fundex-id/bni-api-mgmt/dto"
 	"github.com/fundex-id/bni-api-mgmt/logger"
+	"github.com/fundex-id/bni-api-mgmt/metrics"
+	"github.com/fundex-id/bni-api-mgmt/redact"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/juju/errors"
 	"github.com/lithammer/shortuuid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 var ErrUnauthorized = errors.New("Err StatusUnauthorized")
 
 const (
-	AuthPath              string = "/api/oauth/token"
+	AuthPath              string = config.DefaultAuthPath
 	BalancePath           string = "/H2H/getbalance"
 	InHouseInquiryPath    string = "/H2H/getinhouseinquiry"
 	InterBankInquiryPath  string = "/H2H/getinterbankinquiry"
@@ -36,17 +40,29 @@ const (
 
 type API struct {
 	config     config.Config
-	httpClient *http.Client // for postGetToken only
+	httpClient *http.Client
 
 	mutex       sync.Mutex
 	accessToken string
 	bniSessID   string
+
+	metrics  metrics.Recorder
+	tracer   trace.Tracer
+	logger   *zap.SugaredLogger
+	redactor *redact.Redactor
 }
 
 func newApi(config config.Config) *API {
 	httpClient := cleanhttp.DefaultPooledClient()
+	if tlsConfig := config.TLSConfig(); tlsConfig != nil {
+		httpClient.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+	}
 	api := API{config: config,
 		httpClient: httpClient,
+		metrics:    metrics.Nop{},
+		tracer:     defaultTracer(),
+		logger:     zap.NewNop().Sugar(),
+		redactor:   defaultRedactor(),
 	}
 
 	return &api
@@ -60,8 +76,22 @@ func (api *API) setAccessToken(accessToken string) {
 	api.bniSessID = shortuuid.New()
 }
 
+func (api *API) getAccessToken() string {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	return api.accessToken
+}
+
+func (api *API) getBNISessID() string {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	return api.bniSessID
+}
+
 func (api *API) postGetToken(ctx context.Context) (*dto.GetTokenResponse, error) {
-	urlTarget, err := buildURL(api.config.BNIServer, AuthPath, url.Values{})
+	urlTarget, err := buildURL(api.config.ServerURL(), api.config.TokenPath(), url.Values{})
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -78,7 +108,7 @@ func (api *API) postGetToken(ctx context.Context) (*dto.GetTokenResponse, error)
 	req.Header.Set("content-type", "application/x-www-form-urlencoded")
 	req.SetBasicAuth(api.config.Username, api.config.Password)
 
-	resp, err := api.httpClient.Do(req)
+	resp, err := api.doHTTP(ctx, req)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -90,7 +120,11 @@ func (api *API) postGetToken(ctx context.Context) (*dto.GetTokenResponse, error)
 	}
 
 	api.log(ctx).Info(resp.StatusCode)
-	api.log(ctx).Info(string(bodyRespBytes))
+	api.log(ctx).Info(string(api.redactor.RedactJSON(bodyRespBytes)))
+
+	if err := checkJSONResponse(resp, bodyRespBytes, api.redactor); err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	resp.Body = ioutil.NopCloser(bytes.NewBuffer(bodyRespBytes))
 
@@ -104,8 +138,12 @@ func (api *API) postGetToken(ctx context.Context) (*dto.GetTokenResponse, error)
 	return &dtoResp, nil
 }
 
-func (api *API) doAuthentication(ctx context.Context) (*dto.GetTokenResponse, error) {
-	dtoResp, err := api.postGetToken(ctx)
+func (api *API) doAuthentication(ctx context.Context) (dtoResp *dto.GetTokenResponse, err error) {
+	ctx, span := startSpan(ctx, api.tracer, "BNI token")
+	defer func() { endSpan(span, err) }()
+
+	dtoResp, err = api.postGetToken(ctx)
+	api.metrics.IncTokenRefresh(err)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -169,8 +207,8 @@ func (api *API) postGetInterBankPayment(ctx context.Context, dtoReq *dto.GetInte
 
 // Generic POST request to API
 func (api *API) postToAPI(ctx context.Context, path string, bodyReqPayload []byte) (dtoResp dto.ApiResponse, err error) {
-	urlQuery := url.Values{"access_token": []string{api.accessToken}}
-	urlTarget, err := buildURL(api.config.BNIServer, path, urlQuery)
+	urlQuery := url.Values{"access_token": []string{api.getAccessToken()}}
+	urlTarget, err := buildURL(api.config.ServerURL(), path, urlQuery)
 	if err != nil {
 		return dtoResp, errors.Trace(err)
 	}
@@ -183,7 +221,7 @@ func (api *API) postToAPI(ctx context.Context, path string, bodyReqPayload []byt
 
 	req.Header.Set("content-type", "application/json")
 
-	resp, err := api.httpClient.Do(req)
+	resp, err := api.doHTTP(ctx, req)
 	if err != nil {
 		return dtoResp, errors.Trace(err)
 	}
@@ -197,7 +235,11 @@ func (api *API) postToAPI(ctx context.Context, path string, bodyReqPayload []byt
 	}
 
 	api.log(ctx).Info(resp.StatusCode)
-	api.log(ctx).Info(string(bodyRespBytes))
+	api.log(ctx).Info(string(api.redactor.RedactJSON(bodyRespBytes)))
+
+	if err := checkJSONResponse(resp, bodyRespBytes, api.redactor); err != nil {
+		return dtoResp, errors.Trace(err)
+	}
 
 	resp.Body = ioutil.NopCloser(bytes.NewBuffer(bodyRespBytes))
 
@@ -214,6 +256,7 @@ func (api *API) postToAPIWithRetry(ctx context.Context, path string, bodyReqPayl
 	var dtoResp dto.ApiResponse
 	var err error
 
+	start := time.Now()
 	retryOpts := api.retryOptions(ctx)
 	err = retry.Do(func() error {
 		dtoResp, err = api.postToAPI(ctx, path, bodyReqPayload)
@@ -225,6 +268,7 @@ func (api *API) postToAPIWithRetry(ctx context.Context, path string, bodyReqPayl
 		}
 		return nil
 	}, retryOpts...)
+	dtoResp.Duration = time.Since(start)
 
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -242,8 +286,10 @@ func (api *API) retryDecision(ctx context.Context) func(err error) bool {
 func (api *API) retryOptions(ctx context.Context) []retry.Option {
 	return []retry.Option{
 		retry.Attempts(2),
+		retry.LastErrorOnly(true),
 		retry.RetryIf(api.retryDecision(ctx)),
 		retry.OnRetry(func(n uint, err error) {
+			api.metrics.IncRetry(bniCtx.Operation(ctx))
 			api.log(ctx).Infof("[Retry] === START AUTH === [Attempts: %d Err: %+v]", n, err)
 			api.doAuthentication(ctx)
 			api.log(ctx).Infof("[Retry] === END AUTH ===")
@@ -252,8 +298,33 @@ func (api *API) retryOptions(ctx context.Context) []retry.Option {
 }
 
 // === misc func ===
+
+// doHTTP sends req inside its own span and reports the round trip to the metrics recorder.
+func (api *API) doHTTP(ctx context.Context, req *http.Request) (resp *http.Response, err error) {
+	ctx, span := startSpan(ctx, api.tracer, "HTTP "+req.Method,
+		httpMethodAttr.String(req.Method),
+		httpPathAttr.String(req.URL.Path),
+	)
+	defer func() { endSpan(span, err) }()
+
+	req = req.WithContext(ctx)
+	injectTraceContext(ctx, req)
+
+	start := time.Now()
+	resp, err = api.httpClient.Do(req)
+	err = redactURLError(err)
+
+	var statusCode int
+	if resp != nil {
+		statusCode = resp.StatusCode
+		span.SetAttributes(httpStatusCodeAttr.Int(statusCode))
+	}
+	api.metrics.ObserveHTTP(bniCtx.Operation(ctx), statusCode, time.Since(start))
+
+	return resp, err
+}
 func (api *API) log(ctx context.Context) *zap.SugaredLogger {
-	return logger.Logger(bniCtx.WithBNISessID(ctx, api.bniSessID))
+	return logger.WithContext(api.logger, bniCtx.WithBNISessID(ctx, api.getBNISessID()))
 }
 
 func buildURL(baseUrl, paths string, query url.Values) (string, error) {
@@ -1,14 +1,23 @@
 package bni
 
 import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_buildURL(t *testing.T) {
-	baseUrl := "https://apidev.bni.co.id:8065"
+	sandbox, _ := config.Sandbox.Preset()
+	baseUrl := sandbox.BaseURL
 
 	type args struct {
 		path  string
@@ -34,3 +43,64 @@ func Test_buildURL(t *testing.T) {
 		})
 	}
 }
+
+func Test_isJSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{name: "json content type", contentType: "application/json;charset=UTF-8", body: `{"a":1}`, want: true},
+		{name: "sniffed json", contentType: "text/plain; charset=utf-8", body: ` {"a":1}`, want: true},
+		{name: "no content type", body: `{"a":1}`, want: true},
+		{name: "empty body", contentType: "application/json", body: "", want: false},
+		{name: "html page", contentType: "text/html", body: "<html></html>", want: false},
+		{name: "sniffed html", contentType: "text/plain", body: "<html></html>", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.contentType != "" {
+				header.Set("content-type", tt.contentType)
+			}
+
+			assert.Equal(t, tt.want, isJSONBody(header, []byte(tt.body)))
+		})
+	}
+}
+
+func Test_bodySnippet(t *testing.T) {
+	long := strings.Repeat("x", maxBodySnippet*2)
+
+	assert.Equal(t, "short", bodySnippet([]byte("  short\n")))
+	assert.Equal(t, long[:maxBodySnippet]+"...", bodySnippet([]byte(long)))
+}
+
+func Test_newApi(t *testing.T) {
+	api := newApi(config.Config{Environment: config.Production})
+
+	transport := api.httpClient.Transport.(*http.Transport)
+	if assert.NotNil(t, transport.TLSClientConfig) {
+		assert.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+	}
+
+	api = newApi(config.Config{BNIServer: "http://localhost:8065"})
+	assert.Nil(t, api.httpClient.Transport.(*http.Transport).TLSClientConfig)
+}
+
+func Test_postToAPI_transportErrorHidesAccessToken(t *testing.T) {
+	testServer := httptest.NewServer(http.NotFoundHandler())
+	testServer.Close()
+
+	api := newApi(config.Config{BNIServer: testServer.URL})
+	api.setAccessToken("secret-access-token")
+
+	_, err := api.postToAPI(context.Background(), BalancePath, []byte("{}"))
+	if util.AssertErrNotNil(t, err) {
+		assert.NotContains(t, errors.Details(err), "secret-access-token")
+		assert.Contains(t, err.Error(), BalancePath)
+		_, ok := errors.Cause(err).(*url.Error)
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,101 @@
+package bni
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+type AuditStage string
+
+const (
+	// AuditInitiated is recorded after a payment is signed and before it is sent.
+	AuditInitiated AuditStage = "INITIATED"
+	// AuditResponded is recorded once BNI answered the payment, or the call failed.
+	AuditResponded AuditStage = "RESPONDED"
+	// AuditResolved is recorded when GetPaymentStatus reports the state of a payment.
+	AuditResolved AuditStage = "RESOLVED"
+)
+
+// AuditEvent is one entry of the audit trail of a money movement.
+type AuditEvent struct {
+	Time                    time.Time  `json:"time"`
+	Stage                   AuditStage `json:"stage"`
+	Operation               string     `json:"operation"`
+	Actor                   string     `json:"actor,omitempty"`
+	ClientID                string     `json:"clientId,omitempty"`
+	CustomerReferenceNumber string     `json:"customerReferenceNumber,omitempty"`
+	PayloadHash             string     `json:"payloadHash,omitempty"`
+	BankReference           string     `json:"bankReference,omitempty"`
+	ResponseCode            string     `json:"responseCode,omitempty"`
+	ResponseMessage         string     `json:"responseMessage,omitempty"`
+	TransactionStatus       string     `json:"transactionStatus,omitempty"`
+	Error                   string     `json:"error,omitempty"`
+}
+
+// AuditSink stores audit events. A failure to record AuditInitiated aborts the
+// payment, failures on later stages are only logged.
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+type nopAuditSink struct{}
+
+func (nopAuditSink) Record(ctx context.Context, event AuditEvent) error { return nil }
+
+// auditInitiated records the hash of the signed payload of a payment about to be sent.
+func (b *BNI) auditInitiated(ctx context.Context, crn, payloadHash string) error {
+	err := b.recordAudit(ctx, AuditEvent{
+		Stage:                   AuditInitiated,
+		CustomerReferenceNumber: crn,
+		PayloadHash:             payloadHash,
+	})
+	return errors.Annotate(err, "audit")
+}
+
+// payloadHash fingerprints a request as sent, for the audit trail and the ledger.
+func payloadHash(dtoReq interface{}) (string, error) {
+	payload, err := json.Marshal(dtoReq)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditResponse records the outcome of a payment or of a status lookup.
+func (b *BNI) auditResponse(ctx context.Context, stage AuditStage, crn string, dtoResp *dto.ApiResponse, err error) {
+	param := dtoResp.CommonParam()
+	event := AuditEvent{
+		Stage:                   stage,
+		CustomerReferenceNumber: crn,
+		BankReference:           dtoResp.LogFields().BankReference,
+		ResponseCode:            param.ResponseCode,
+		ResponseMessage:         param.ResponseMessage,
+	}
+	if dtoResp != nil && dtoResp.GetPaymentStatusResponse != nil {
+		event.TransactionStatus = dtoResp.GetPaymentStatusResponse.Parameters.PreviousResponse.TransactionStatus
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	if err := b.recordAudit(ctx, event); err != nil {
+		b.log(ctx).Error(errors.Details(errors.Annotate(err, "audit")))
+	}
+}
+
+func (b *BNI) recordAudit(ctx context.Context, event AuditEvent) error {
+	event.Time = time.Now().UTC()
+	event.Operation = bniCtx.Operation(ctx)
+	event.Actor = bniCtx.Actor(ctx)
+	event.ClientID = b.config.ClientID
+
+	return b.auditSink.Record(ctx, event)
+}
@@ -0,0 +1,157 @@
+package bni
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// ErrAuditChainBroken is returned by VerifyAuditFile when a record was altered, removed or reordered.
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// auditRecord is one line of a FileAuditSink file. Hash covers PrevHash and Event,
+// so changing any line invalidates every line after it.
+type auditRecord struct {
+	Event    AuditEvent `json:"event"`
+	PrevHash string     `json:"prevHash"`
+	Hash     string     `json:"hash"`
+}
+
+// FileAuditSink appends hash-chained audit events to a JSON-lines file.
+type FileAuditSink struct {
+	mutex    sync.Mutex
+	file     *os.File
+	prevHash string
+}
+
+var _ AuditSink = (*FileAuditSink)(nil)
+
+// NewFileAuditSink opens (or creates) path and continues the chain from its last record.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	prevHash, err := lastAuditHash(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return &FileAuditSink{file: file, prevHash: prevHash}, nil
+}
+
+// Record appends event and syncs the file before returning.
+func (s *FileAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hash, err := auditHash(s.prevHash, event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	line, err := json.Marshal(auditRecord{Event: event, PrevHash: s.prevHash, Hash: hash})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return errors.Trace(err)
+	}
+
+	s.prevHash = hash
+	return nil
+}
+
+func (s *FileAuditSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
+
+// VerifyAuditFile checks the hash chain of a file written by FileAuditSink
+// and returns the number of valid records.
+func VerifyAuditFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer file.Close()
+
+	var count int
+	var prevHash string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return count, errors.Annotatef(err, "line %d", count+1)
+		}
+
+		hash, err := auditHash(prevHash, record.Event)
+		if err != nil {
+			return count, errors.Trace(err)
+		}
+		if record.PrevHash != prevHash || record.Hash != hash {
+			return count, errors.Annotatef(ErrAuditChainBroken, "line %d", count+1)
+		}
+
+		prevHash = record.Hash
+		count++
+	}
+
+	return count, errors.Trace(scanner.Err())
+}
+
+func lastAuditHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer file.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		last = append(last[:0], scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(last) == 0 {
+		return "", nil
+	}
+
+	var record auditRecord
+	if err := json.Unmarshal(last, &record); err != nil {
+		return "", errors.Annotate(err, "last audit record")
+	}
+	return record.Hash, nil
+}
+
+func auditHash(prevHash string, event AuditEvent) (string, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,88 @@
+package bni
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBNI_WithAuditSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bni-audit")
+	util.AssertErrNil(t, err)
+	defer os.RemoveAll(dir)
+	auditPath := filepath.Join(dir, "audit.jsonl")
+
+	sink, err := NewFileAuditSink(auditPath)
+	util.AssertErrNil(t, err)
+
+	givenConfig := config.Config{
+		ClientID:        "IDBNITEST",
+		SignatureConfig: dummySignatureConfig,
+	}
+
+	bni, testServer := buildBNIAndMockServerGoodResponse(t, givenConfig,
+		InHouseTransferPath, "testdata/get_dopayment_response.json", WithAuditSink(sink))
+	defer testServer.Close()
+
+	ctx := bniCtx.WithActor(context.Background(), "finance-ops")
+	_, err = bni.DoPayment(ctx, &dto.DoPaymentRequest{
+		CustomerReferenceNumber: "20170227000000000020",
+		DebitAccountNo:          "113183203",
+		CreditAccountNo:         "115471119",
+		ValueAmount:             "100500",
+		ValueCurrency:           "IDR",
+	})
+	util.AssertErrNil(t, err)
+
+	bni, statusServer := buildBNIAndMockServerGoodResponse(t, givenConfig,
+		PaymentStatusPath, "testdata/get_getpaymentstatus_response.json", WithAuditSink(sink))
+	defer statusServer.Close()
+
+	_, err = bni.GetPaymentStatus(ctx, &dto.GetPaymentStatusRequest{CustomerReferenceNumber: "20170227000000000020"})
+	util.AssertErrNil(t, err)
+	util.AssertErrNil(t, sink.Close())
+
+	count, err := VerifyAuditFile(auditPath)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, 3, count)
+
+	content, err := ioutil.ReadFile(auditPath)
+	util.AssertErrNil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], `"stage":"INITIATED"`)
+		assert.Contains(t, lines[0], `"actor":"finance-ops"`)
+		assert.Contains(t, lines[0], `"payloadHash":"`)
+		assert.Contains(t, lines[1], `"stage":"RESPONDED"`)
+		assert.Contains(t, lines[1], `"bankReference":"953403"`)
+		assert.Contains(t, lines[2], `"stage":"RESOLVED"`)
+		assert.Contains(t, lines[2], `"transactionStatus":"Y"`)
+	}
+
+	// reopening continues the chain
+	sink, err = NewFileAuditSink(auditPath)
+	util.AssertErrNil(t, err)
+	util.AssertErrNil(t, sink.Record(ctx, AuditEvent{Stage: AuditResolved}))
+	util.AssertErrNil(t, sink.Close())
+
+	count, err = VerifyAuditFile(auditPath)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, 4, count)
+
+	tampered := strings.Replace(string(content), `"bankReference":"953403"`, `"bankReference":"953404"`, 1)
+	util.AssertErrNil(t, ioutil.WriteFile(auditPath, []byte(tampered), 0600))
+
+	count, err = VerifyAuditFile(auditPath)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, ErrAuditChainBroken, errors.Cause(err))
+}
@@ -0,0 +1,53 @@
+package balance
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// History stores the samples of a Watcher.
+type History interface {
+	Record(ctx context.Context, sample Sample) error
+	// Samples returns the samples of account taken at or after since, oldest first.
+	Samples(ctx context.Context, account string, since time.Time) ([]Sample, error)
+}
+
+// MemoryHistory keeps the latest samples of each account in memory.
+type MemoryHistory struct {
+	mutex   sync.Mutex
+	limit   int
+	samples map[string][]Sample
+}
+
+var _ History = (*MemoryHistory)(nil)
+
+// NewMemoryHistory keeps up to limit samples per account, all of them when limit is 0.
+func NewMemoryHistory(limit int) *MemoryHistory {
+	return &MemoryHistory{limit: limit, samples: map[string][]Sample{}}
+}
+
+func (h *MemoryHistory) Record(ctx context.Context, sample Sample) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	samples := append(h.samples[sample.Account], sample)
+	if h.limit > 0 && len(samples) > h.limit {
+		samples = append([]Sample(nil), samples[len(samples)-h.limit:]...)
+	}
+	h.samples[sample.Account] = samples
+	return nil
+}
+
+func (h *MemoryHistory) Samples(ctx context.Context, account string, since time.Time) ([]Sample, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var samples []Sample
+	for _, sample := range h.samples[account] {
+		if !sample.At.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,275 @@
+// Package balance watches account balances with GetBalance and raises
+// alerts before payouts start failing for insufficient funds:
+//
+//	w := balance.NewWatcher(client, []balance.Account{{Number: "113183203", LowBalance: 50000000}},
+//		balance.WithInterval(5*time.Minute),
+//		balance.WithAlerter(balance.NewWebhook("https://hooks.example.com/bni")))
+//	go w.Run(ctx)
+package balance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+const DefaultInterval = 5 * time.Minute
+
+// Account is an account to watch and what to alert on, a zero limit is not checked.
+type Account struct {
+	Number string
+	// LowBalance alerts once the balance falls below it, and again only after
+	// it went back up to it. An alert no alerter delivered is sent again on the
+	// next poll.
+	LowBalance int64
+	// MaxChange alerts when the balance moves by more than it between two polls.
+	MaxChange int64
+}
+
+// Sample is a balance read at a point in time.
+type Sample struct {
+	Account  string    `json:"account"`
+	Balance  int64     `json:"balance"`
+	Currency string    `json:"currency"`
+	At       time.Time `json:"at"`
+}
+
+type AlertKind string
+
+const (
+	// LowBalance is a balance that fell below Account.LowBalance.
+	LowBalance AlertKind = "LOW_BALANCE"
+	// Recovered is a balance back at or above Account.LowBalance.
+	Recovered AlertKind = "RECOVERED"
+	// UnexpectedChange is a move of more than Account.MaxChange since the last poll.
+	UnexpectedChange AlertKind = "UNEXPECTED_CHANGE"
+)
+
+// Alert tells about one account. Limit is the threshold or maximum change it crossed.
+type Alert struct {
+	Kind     AlertKind `json:"kind"`
+	Account  string    `json:"account"`
+	Balance  int64     `json:"balance"`
+	Previous int64     `json:"previous"`
+	Limit    int64     `json:"limit"`
+	Currency string    `json:"currency"`
+	At       time.Time `json:"at"`
+	Message  string    `json:"message"`
+}
+
+// Alerter delivers alerts, e.g. to a chat webhook or a pager.
+type Alerter interface {
+	Alert(ctx context.Context, alert Alert) error
+}
+
+// AlerterFunc adapts a function to Alerter.
+type AlerterFunc func(ctx context.Context, alert Alert) error
+
+func (f AlerterFunc) Alert(ctx context.Context, alert Alert) error {
+	return f(ctx, alert)
+}
+
+type Option func(*Watcher)
+
+func WithInterval(interval time.Duration) Option {
+	return func(w *Watcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+// WithAlerter adds an alerter, every alert goes to each of them.
+func WithAlerter(alerter Alerter) Option {
+	return func(w *Watcher) {
+		w.alerters = append(w.alerters, alerter)
+	}
+}
+
+// WithHistory records every sample to history, a MemoryHistory keeping the
+// last 1000 samples per account by default.
+func WithHistory(history History) Option {
+	return func(w *Watcher) {
+		w.history = history
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(w *Watcher) {
+		w.now = now
+	}
+}
+
+// WithErrorHandler calls fn with the errors Run would otherwise drop: failed
+// polls, history writes and alert deliveries.
+func WithErrorHandler(fn func(error)) Option {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+// Watcher polls the balance of accounts and alerts on them.
+type Watcher struct {
+	client   bni.Client
+	accounts []Account
+	interval time.Duration
+	alerters []Alerter
+	history  History
+	now      func() time.Time
+	onError  func(error)
+
+	mutex sync.Mutex
+	last  map[string]Sample
+	low   map[string]bool
+}
+
+func NewWatcher(client bni.Client, accounts []Account, opts ...Option) *Watcher {
+	w := &Watcher{
+		client:   client,
+		accounts: accounts,
+		interval: DefaultInterval,
+		history:  NewMemoryHistory(1000),
+		now:      time.Now,
+		onError:  func(error) {},
+		last:     map[string]Sample{},
+		low:      map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run polls every interval until ctx is done, which is the error it returns.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.PollOnce(ctx); err != nil && ctx.Err() == nil {
+			w.onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce reads every account once and sends the alerts due. An account
+// failing does not stop the others, the first error is returned.
+func (w *Watcher) PollOnce(ctx context.Context) error {
+	var firstErr error
+	keep := func(err error) {
+		if err == nil {
+			return
+		}
+		if firstErr == nil {
+			firstErr = err
+		} else {
+			w.onError(err)
+		}
+	}
+
+	for _, account := range w.accounts {
+		resp, err := w.client.GetBalance(ctx, &dto.GetBalanceRequest{AccountNo: account.Number})
+		if err != nil {
+			keep(errors.Annotatef(err, "balance of %s", account.Number))
+			continue
+		}
+
+		sample := Sample{
+			Account:  account.Number,
+			Balance:  resp.Parameters.AccountBalance,
+			Currency: resp.Parameters.AccountCurrency,
+			At:       w.now().UTC(),
+		}
+		keep(errors.Annotate(w.history.Record(ctx, sample), "history"))
+
+		for _, alert := range w.check(account, sample) {
+			delivered := false
+			for _, alerter := range w.alerters {
+				err := alerter.Alert(ctx, alert)
+				keep(errors.Annotatef(err, "%s alert for %s", alert.Kind, alert.Account))
+				delivered = delivered || err == nil
+			}
+			if delivered {
+				w.alerted(alert)
+			}
+		}
+	}
+	return firstErr
+}
+
+// Last returns the latest sample of an account.
+func (w *Watcher) Last(account string) (Sample, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	sample, ok := w.last[account]
+	return sample, ok
+}
+
+// check compares sample with the previous one and returns the alerts due.
+// The low balance state only changes once an alert about it is delivered.
+func (w *Watcher) check(account Account, sample Sample) []Alert {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	previous, hasPrevious := w.last[account.Number]
+	w.last[account.Number] = sample
+
+	newAlert := func(kind AlertKind, limit int64, format string, args ...interface{}) Alert {
+		return Alert{
+			Kind:     kind,
+			Account:  account.Number,
+			Balance:  sample.Balance,
+			Previous: previous.Balance,
+			Limit:    limit,
+			Currency: sample.Currency,
+			At:       sample.At,
+			Message:  fmt.Sprintf("account %s: ", account.Number) + fmt.Sprintf(format, args...),
+		}
+	}
+
+	var alerts []Alert
+	if account.LowBalance > 0 {
+		low := sample.Balance < account.LowBalance
+		switch {
+		case low && !w.low[account.Number]:
+			alerts = append(alerts, newAlert(LowBalance, account.LowBalance,
+				"balance %d %s below %d", sample.Balance, sample.Currency, account.LowBalance))
+		case !low && w.low[account.Number]:
+			alerts = append(alerts, newAlert(Recovered, account.LowBalance,
+				"balance %d %s back above %d", sample.Balance, sample.Currency, account.LowBalance))
+		}
+	}
+
+	if account.MaxChange > 0 && hasPrevious {
+		change := sample.Balance - previous.Balance
+		if change > account.MaxChange || -change > account.MaxChange {
+			alerts = append(alerts, newAlert(UnexpectedChange, account.MaxChange,
+				"balance moved by %+d %s since %s", change, sample.Currency, previous.At.Format(time.RFC3339)))
+		}
+	}
+	return alerts
+}
+
+// alerted records a delivered alert in the low balance state.
+func (w *Watcher) alerted(alert Alert) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	switch alert.Kind {
+	case LowBalance:
+		w.low[alert.Account] = true
+	case Recovered:
+		w.low[alert.Account] = false
+	}
+}
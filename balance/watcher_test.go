@@ -0,0 +1,147 @@
+package balance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/bnifake"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func balanceResponse(balance int64) *dto.GetBalanceResponse {
+	resp := &dto.GetBalanceResponse{}
+	resp.Parameters.AccountBalance = balance
+	resp.Parameters.AccountCurrency = "IDR"
+	return resp
+}
+
+func TestWatcher_PollOnce(t *testing.T) {
+	fake := bnifake.New()
+	for _, balance := range []int64{1000000, 400000, 300000, 1200000} {
+		fake.QueueGetBalance(balanceResponse(balance), nil)
+	}
+
+	now := time.Date(2017, 2, 27, 8, 0, 0, 0, time.UTC)
+	var alerts []Alert
+	history := NewMemoryHistory(3)
+	w := NewWatcher(fake, []Account{{Number: "113183203", LowBalance: 500000, MaxChange: 500000}},
+		WithHistory(history),
+		WithClock(func() time.Time { return now }),
+		WithAlerter(AlerterFunc(func(ctx context.Context, alert Alert) error {
+			alerts = append(alerts, alert)
+			return nil
+		})),
+	)
+
+	var kinds [][]AlertKind
+	for i := 0; i < 4; i++ {
+		alerts = nil
+		util.AssertErrNil(t, w.PollOnce(context.Background()))
+		var polled []AlertKind
+		for _, alert := range alerts {
+			polled = append(polled, alert.Kind)
+		}
+		kinds = append(kinds, polled)
+		now = now.Add(time.Hour)
+	}
+
+	assert.Equal(t, [][]AlertKind{
+		nil,
+		{LowBalance, UnexpectedChange},
+		nil, // still low, alerted already
+		{Recovered, UnexpectedChange},
+	}, kinds)
+	assert.Equal(t, "account 113183203: balance moved by +900000 IDR since 2017-02-27T10:00:00Z", alerts[1].Message)
+
+	samples, err := history.Samples(context.Background(), "113183203", time.Time{})
+	util.AssertErrNil(t, err)
+	if assert.Len(t, samples, 3) {
+		assert.Equal(t, int64(400000), samples[0].Balance)
+	}
+
+	last, ok := w.Last("113183203")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1200000), last.Balance)
+}
+
+func TestWatcher_PollOnceErrors(t *testing.T) {
+	fake := bnifake.New().
+		QueueGetBalance(nil, errors.New("connection reset")).
+		QueueGetBalance(balanceResponse(1), nil)
+
+	var alerted []string
+	w := NewWatcher(fake, []Account{{Number: "1", LowBalance: 10}, {Number: "2", LowBalance: 10}},
+		WithAlerter(AlerterFunc(func(ctx context.Context, alert Alert) error {
+			alerted = append(alerted, alert.Account)
+			return nil
+		})))
+
+	err := w.PollOnce(context.Background())
+	assert.Contains(t, err.Error(), "balance of 1")
+	assert.Equal(t, []string{"2"}, alerted, "a failing account does not stop the others")
+}
+
+func TestWatcher_PollOnceUndelivered(t *testing.T) {
+	fake := bnifake.New()
+	for _, balance := range []int64{1, 1, 1, 20, 20} {
+		fake.QueueGetBalance(balanceResponse(balance), nil)
+	}
+
+	down := true
+	var alerts []AlertKind
+	w := NewWatcher(fake, []Account{{Number: "1", LowBalance: 10}},
+		WithAlerter(AlerterFunc(func(ctx context.Context, alert Alert) error {
+			return errors.New("pager down")
+		})),
+		WithAlerter(AlerterFunc(func(ctx context.Context, alert Alert) error {
+			if down {
+				return errors.New("chat down")
+			}
+			alerts = append(alerts, alert.Kind)
+			return nil
+		})))
+
+	util.AssertErrNotNil(t, w.PollOnce(context.Background()))
+	util.AssertErrNotNil(t, w.PollOnce(context.Background()))
+	assert.Empty(t, alerts)
+
+	down = false
+	w.PollOnce(context.Background())
+	w.PollOnce(context.Background())
+	w.PollOnce(context.Background())
+	assert.Equal(t, []AlertKind{LowBalance, Recovered}, alerts, "sent again until one alerter delivers it")
+}
+
+func TestWebhook(t *testing.T) {
+	var received Alert
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth = req.Header.Get("Authorization")
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if received.Account == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	hook := NewWebhook(server.URL, WithWebhookHeader("Authorization", "Bearer token"))
+	util.AssertErrNil(t, hook.Alert(context.Background(), Alert{Kind: LowBalance, Account: "113183203", Balance: 1}))
+	assert.Equal(t, "Bearer token", auth)
+	assert.Equal(t, LowBalance, received.Kind)
+	assert.Equal(t, int64(1), received.Balance)
+
+	err := hook.Alert(context.Background(), Alert{Account: "fail"})
+	if util.AssertErrNotNil(t, err) {
+		assert.Contains(t, err.Error(), "500")
+	}
+}
@@ -0,0 +1,79 @@
+package balance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+type WebhookOption func(*Webhook)
+
+// WithWebhookClient sets the HTTP client, one with a 10s timeout by default.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(h *Webhook) {
+		h.client = client
+	}
+}
+
+// WithWebhookHeader adds a header to every request, e.g. an Authorization token.
+func WithWebhookHeader(name, value string) WebhookOption {
+	return func(h *Webhook) {
+		h.header.Add(name, value)
+	}
+}
+
+// Webhook is an Alerter posting each alert as a JSON object to a URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+var _ Alerter = (*Webhook)(nil)
+
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	h := &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		header: http.Header{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Alert fails unless the receiver answers with a 2xx status.
+func (h *Webhook) Alert(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for name, values := range h.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
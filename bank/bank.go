@@ -0,0 +1,193 @@
+// Package bank lists the Indonesian banks BNI transfers to, by the 3-digit
+// code used in DestinationBankCode, and checks interbank requests against it:
+//
+//	registry := bank.Default()
+//	b, ok := registry.Find("mandiri") // by code, name or alias
+//	client := bni.New(cfg, bni.WithRequestValidator(registry))
+//
+// The built-in list is a partial, unverified starting point; load the list
+// BNI gives you with LoadFile, and ReloadFile when it changes. A code missing
+// from the list is not rejected, only its format is checked.
+package bank
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/juju/errors"
+)
+
+// BNICode is BNI's own bank code, transfers to it are in-house.
+const BNICode = "009"
+
+// Rail is a way money reaches another bank.
+type Rail string
+
+const (
+	// Online is a real-time transfer, GetInterBankPayment.
+	Online Rail = "ONLINE"
+	// RTGS is DoPayment with payment method 1.
+	RTGS Rail = "RTGS"
+	// Clearing is SKN, DoPayment with payment method 2.
+	Clearing Rail = "CLEARING"
+)
+
+var rails = []Rail{Online, RTGS, Clearing}
+
+// Bank is one destination bank.
+type Bank struct {
+	Code    string   `json:"code" yaml:"code"`
+	Name    string   `json:"name" yaml:"name"`
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Rails   []Rail   `json:"rails" yaml:"rails"`
+}
+
+// Supports tells whether the bank can be reached over rail.
+func (b Bank) Supports(rail Rail) bool {
+	for _, r := range b.Rails {
+		if r == rail {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry looks banks up by code, name or alias. It is safe for concurrent
+// use, ReloadFile swaps the list under running lookups.
+type Registry struct {
+	mutex  sync.RWMutex
+	byCode map[string]Bank
+	byName map[string]Bank
+}
+
+var _ bni.RequestValidator = (*Registry)(nil)
+
+// New checks banks and indexes them: codes are 3 digits, and codes, names
+// and aliases are unique once normalized.
+func New(banks []Bank) (*Registry, error) {
+	r := &Registry{}
+	if err := r.Replace(banks); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Default returns a registry of the built-in list.
+func Default() *Registry {
+	r, err := New(builtin)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Replace swaps the list of the registry, which is left as it was when banks is invalid.
+func (r *Registry) Replace(banks []Bank) error {
+	byCode := map[string]Bank{}
+	byName := map[string]Bank{}
+	for i, bank := range banks {
+		if !validCode(bank.Code) {
+			return errors.NotValidf("bank %d code %q, it must be 3 digits", i, bank.Code)
+		}
+		if _, exist := byCode[bank.Code]; exist {
+			return errors.NotValidf("duplicate bank code %s", bank.Code)
+		}
+		if strings.TrimSpace(bank.Name) == "" {
+			return errors.NotValidf("bank %s without name", bank.Code)
+		}
+		for _, rail := range bank.Rails {
+			if !knownRail(rail) {
+				return errors.NotValidf("bank %s rail %q", bank.Code, rail)
+			}
+		}
+
+		bank.Aliases = append([]string(nil), bank.Aliases...)
+		bank.Rails = append([]Rail(nil), bank.Rails...)
+		byCode[bank.Code] = bank
+
+		for _, name := range append([]string{bank.Name}, bank.Aliases...) {
+			key := normalize(name)
+			if key == "" {
+				continue
+			}
+			if other, exist := byName[key]; exist && other.Code != bank.Code {
+				return errors.NotValidf("name %q of bank %s, already used by bank %s", name, bank.Code, other.Code)
+			}
+			byName[key] = bank
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.byCode, r.byName = byCode, byName
+	return nil
+}
+
+// Lookup returns the bank with code.
+func (r *Registry) Lookup(code string) (Bank, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	bank, ok := r.byCode[strings.TrimSpace(code)]
+	return bank, ok
+}
+
+// ByName returns the bank with a name or alias matching name, ignoring case,
+// punctuation and words such as "Bank", "PT" and "Tbk".
+func (r *Registry) ByName(name string) (Bank, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	bank, ok := r.byName[normalize(name)]
+	return bank, ok
+}
+
+// Find looks query up as a code, then as a name or alias.
+func (r *Registry) Find(query string) (Bank, bool) {
+	if bank, ok := r.Lookup(query); ok {
+		return bank, true
+	}
+	return r.ByName(query)
+}
+
+// Banks returns every bank, by code.
+func (r *Registry) Banks() []Bank {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	banks := make([]Bank, 0, len(r.byCode))
+	for _, bank := range r.byCode {
+		banks = append(banks, bank)
+	}
+	sort.Slice(banks, func(i, j int) bool { return banks[i].Code < banks[j].Code })
+	return banks
+}
+
+func validCode(code string) bool {
+	return len(code) == 3 && strings.Trim(code, "0123456789") == ""
+}
+
+func knownRail(rail Rail) bool {
+	for _, known := range rails {
+		if rail == known {
+			return true
+		}
+	}
+	return false
+}
+
+// noise are the words left out when comparing names.
+var noise = map[string]bool{"BANK": true, "PT": true, "TBK": true, "PERSERO": true}
+
+func normalize(name string) string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !noise[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,111 @@
+package bank
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Find(t *testing.T) {
+	r := Default()
+	for _, query := range []string{"014", "BCA", "bank central asia", "PT Bank Central Asia Tbk", " bca "} {
+		b, ok := r.Find(query)
+		if assert.True(t, ok, query) {
+			assert.Equal(t, "014", b.Code, query)
+		}
+	}
+
+	_, ok := r.Find("Bank Nowhere")
+	assert.False(t, ok)
+
+	banks := r.Banks()
+	assert.Equal(t, "002", banks[0].Code)
+	assert.Len(t, banks, len(builtin))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := map[string][]Bank{
+		"code":      {{Code: "14", Name: "BCA"}},
+		"duplicate": {{Code: "014", Name: "BCA"}, {Code: "014", Name: "Other"}},
+		"name":      {{Code: "014"}},
+		"rail":      {{Code: "014", Name: "BCA", Rails: []Rail{"SWIFT"}}},
+		"alias":     {{Code: "014", Name: "BCA"}, {Code: "015", Name: "Other", Aliases: []string{"PT BCA"}}},
+	}
+	for name, banks := range tests {
+		_, err := New(banks)
+		assert.True(t, errors.IsNotValid(err), name)
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	r := Default()
+
+	payment := &dto.GetInterBankPaymentRequest{DestinationBankCode: "014"}
+	util.AssertErrNil(t, r.ValidateInterBankPayment(payment))
+	assert.Equal(t, "Bank Central Asia", payment.DestinationBankName)
+
+	payment = &dto.GetInterBankPaymentRequest{DestinationBankCode: "014", DestinationBankName: "PT. BANK CENTRAL ASIA TBK."}
+	util.AssertErrNil(t, r.ValidateInterBankPayment(payment))
+	assert.Equal(t, "PT. BANK CENTRAL ASIA TBK.", payment.DestinationBankName, "the name from the inquiry is kept")
+
+	payment = &dto.GetInterBankPaymentRequest{DestinationBankCode: "999"}
+	assert.NoError(t, r.ValidateInterBankPayment(payment), "unlisted banks are left to BNI")
+	assert.Empty(t, payment.DestinationBankName)
+
+	err := r.ValidateInterBankInquiry(&dto.GetInterBankInquiryRequest{DestinationBankCode: "99"})
+	assert.True(t, errors.IsNotValid(err))
+	err = r.ValidateInterBankInquiry(&dto.GetInterBankInquiryRequest{DestinationBankCode: BNICode})
+	assert.True(t, errors.IsNotValid(err))
+	err = r.ValidateInterBankInquiry(&dto.GetInterBankInquiryRequest{DestinationBankCode: "031"})
+	assert.True(t, errors.IsNotSupported(err), "no online transfer to Citibank")
+
+	util.AssertErrNil(t, r.ValidateDoPayment(&dto.DoPaymentRequest{PaymentMethod: "0"}))
+	util.AssertErrNil(t, r.ValidateDoPayment(&dto.DoPaymentRequest{PaymentMethod: "1", DestinationBankCode: "031"}))
+	err = r.ValidateDoPayment(&dto.DoPaymentRequest{PaymentMethod: "2"})
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	util.AssertErrNil(t, err)
+	defer os.RemoveAll(dir)
+
+	yamlPath := filepath.Join(dir, "banks.yaml")
+	util.AssertErrNil(t, ioutil.WriteFile(yamlPath, []byte(`
+- code: "014"
+  name: Bank Central Asia
+  aliases: [BCA]
+  rails: [ONLINE]
+`), 0600))
+	r, err := LoadFile(yamlPath)
+	if util.AssertErrNil(t, err) {
+		b, ok := r.Find("bca")
+		assert.True(t, ok)
+		assert.Equal(t, []Rail{Online}, b.Rails)
+	}
+
+	jsonPath := filepath.Join(dir, "banks.json")
+	util.AssertErrNil(t, ioutil.WriteFile(jsonPath, []byte(`[{"code": "002", "name": "BRI", "rails": ["RTGS"], "swift": "BRINIDJA"}]`), 0600))
+	err = r.ReloadFile(jsonPath)
+	if util.AssertErrNotNil(t, err) {
+		assert.Contains(t, err.Error(), "swift")
+	}
+	_, ok := r.Lookup("014")
+	assert.True(t, ok, "kept on a failed reload")
+
+	util.AssertErrNil(t, ioutil.WriteFile(jsonPath, []byte(`[{"code": "002", "name": "BRI", "rails": ["RTGS"]}]`), 0600))
+	util.AssertErrNil(t, r.ReloadFile(jsonPath))
+	_, ok = r.Lookup("014")
+	assert.False(t, ok)
+	_, ok = r.ByName("bri")
+	assert.True(t, ok)
+
+	_, err = LoadFile(filepath.Join(dir, "banks.csv"))
+	util.AssertErrNotNil(t, err)
+}
@@ -0,0 +1,43 @@
+package bank
+
+var allRails = []Rail{Online, RTGS, Clearing}
+
+// builtin lists the banks payouts most often go to, it leaves out most
+// regional development banks (BPD) and rural banks and is not checked against
+// BNI's participant list. Load the list BNI gives you with LoadFile rather
+// than editing this one.
+var builtin = []Bank{
+	{Code: "002", Name: "Bank Rakyat Indonesia", Aliases: []string{"BRI"}, Rails: allRails},
+	{Code: "008", Name: "Bank Mandiri", Aliases: []string{"Mandiri"}, Rails: allRails},
+	{Code: "009", Name: "Bank Negara Indonesia", Aliases: []string{"BNI", "BNI 46"}, Rails: allRails},
+	{Code: "011", Name: "Bank Danamon Indonesia", Aliases: []string{"Danamon"}, Rails: allRails},
+	{Code: "013", Name: "Bank Permata", Aliases: []string{"Permata", "PermataBank"}, Rails: allRails},
+	{Code: "014", Name: "Bank Central Asia", Aliases: []string{"BCA"}, Rails: allRails},
+	{Code: "016", Name: "Bank Maybank Indonesia", Aliases: []string{"Maybank", "BII"}, Rails: allRails},
+	{Code: "019", Name: "Bank Panin", Aliases: []string{"Panin", "Panin Bank"}, Rails: allRails},
+	{Code: "022", Name: "Bank CIMB Niaga", Aliases: []string{"CIMB", "CIMB Niaga", "Niaga"}, Rails: allRails},
+	{Code: "023", Name: "Bank UOB Indonesia", Aliases: []string{"UOB"}, Rails: allRails},
+	{Code: "028", Name: "Bank OCBC NISP", Aliases: []string{"OCBC", "NISP"}, Rails: allRails},
+	{Code: "031", Name: "Citibank", Aliases: []string{"Citi"}, Rails: []Rail{RTGS, Clearing}},
+	{Code: "046", Name: "Bank DBS Indonesia", Aliases: []string{"DBS"}, Rails: allRails},
+	{Code: "050", Name: "Standard Chartered Bank", Aliases: []string{"Standard Chartered", "StanChart", "SCB"}, Rails: []Rail{RTGS, Clearing}},
+	{Code: "087", Name: "HSBC Indonesia", Aliases: []string{"HSBC"}, Rails: allRails},
+	{Code: "110", Name: "Bank BJB", Aliases: []string{"BJB", "Bank Jabar Banten"}, Rails: allRails},
+	{Code: "111", Name: "Bank DKI", Aliases: []string{"DKI"}, Rails: allRails},
+	{Code: "112", Name: "BPD DIY", Aliases: []string{"Bank BPD DIY"}, Rails: allRails},
+	{Code: "113", Name: "Bank Jateng", Aliases: []string{"Jateng", "BPD Jawa Tengah"}, Rails: allRails},
+	{Code: "114", Name: "Bank Jatim", Aliases: []string{"Jatim", "BPD Jawa Timur"}, Rails: allRails},
+	{Code: "129", Name: "BPD Bali", Aliases: []string{"Bank BPD Bali"}, Rails: allRails},
+	{Code: "147", Name: "Bank Muamalat Indonesia", Aliases: []string{"Muamalat"}, Rails: allRails},
+	{Code: "153", Name: "Bank Sinarmas", Aliases: []string{"Sinarmas"}, Rails: allRails},
+	{Code: "200", Name: "Bank Tabungan Negara", Aliases: []string{"BTN"}, Rails: allRails},
+	{Code: "213", Name: "Bank BTPN", Aliases: []string{"BTPN", "SMBC Indonesia", "Jenius"}, Rails: allRails},
+	{Code: "426", Name: "Bank Mega", Aliases: []string{"Mega"}, Rails: allRails},
+	{Code: "441", Name: "Bank KB Bukopin", Aliases: []string{"Bukopin", "KB Bukopin"}, Rails: allRails},
+	{Code: "451", Name: "Bank Syariah Indonesia", Aliases: []string{"BSI", "BSM", "Mandiri Syariah"}, Rails: allRails},
+	{Code: "484", Name: "Bank KEB Hana Indonesia", Aliases: []string{"KEB Hana", "Hana"}, Rails: allRails},
+	{Code: "490", Name: "Bank Neo Commerce", Aliases: []string{"BNC", "Neo Commerce"}, Rails: allRails},
+	{Code: "535", Name: "SeaBank Indonesia", Aliases: []string{"SeaBank", "Bank Kesejahteraan Ekonomi"}, Rails: allRails},
+	{Code: "542", Name: "Bank Jago", Aliases: []string{"Jago", "Bank Artos"}, Rails: allRails},
+	{Code: "567", Name: "Allo Bank Indonesia", Aliases: []string{"Allo", "Allo Bank"}, Rails: allRails},
+}
@@ -0,0 +1,68 @@
+package bank
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadFile reads a list of banks from a YAML (.yaml, .yml) or JSON (.json)
+// file, such as:
+//
+//	# banks.yaml
+//	- code: "014"
+//	  name: Bank Central Asia
+//	  aliases: [BCA]
+//	  rails: [ONLINE, RTGS, CLEARING]
+//
+// Codes must be quoted in YAML, unquoted they lose their leading zeros.
+func LoadFile(path string) (*Registry, error) {
+	banks, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := New(banks)
+	if err != nil {
+		return nil, errors.Annotate(err, path)
+	}
+	return r, nil
+}
+
+// ReloadFile replaces the list of r with the one in path, see LoadFile. The
+// current list is kept when the file cannot be read or is invalid.
+func (r *Registry) ReloadFile(path string) error {
+	banks, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	return errors.Annotate(r.Replace(banks), path)
+}
+
+func readFile(path string) ([]Bank, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var banks []Bank
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(content, &banks)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&banks)
+	default:
+		return nil, errors.NotSupportedf("bank list file extension %q", ext)
+	}
+	if err != nil {
+		return nil, errors.Annotate(err, path)
+	}
+	return banks, nil
+}
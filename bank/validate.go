@@ -0,0 +1,69 @@
+package bank
+
+import (
+	"strings"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+// methodRails are the rails of the DoPayment payment methods other than 0, in-house.
+var methodRails = map[string]Rail{"1": RTGS, "2": Clearing}
+
+// ValidateDoPayment checks the destination bank of an RTGS or clearing
+// payment supports the rail. In-house payments are left alone.
+func (r *Registry) ValidateDoPayment(dtoReq *dto.DoPaymentRequest) error {
+	rail, ok := methodRails[dtoReq.PaymentMethod]
+	if !ok {
+		return nil
+	}
+	_, err := r.destination(dtoReq.DestinationBankCode, rail)
+	return err
+}
+
+// ValidateInterBankInquiry checks the destination bank takes online transfers.
+func (r *Registry) ValidateInterBankInquiry(dtoReq *dto.GetInterBankInquiryRequest) error {
+	_, err := r.destination(dtoReq.DestinationBankCode, Online)
+	return err
+}
+
+// ValidateInterBankPayment checks the destination bank takes online
+// transfers. An empty DestinationBankName is filled for a listed bank, one
+// that is set is left alone: it is usually the free text name BNI's inquiry
+// returned.
+func (r *Registry) ValidateInterBankPayment(dtoReq *dto.GetInterBankPaymentRequest) error {
+	bank, err := r.destination(dtoReq.DestinationBankCode, Online)
+	if err != nil {
+		return err
+	}
+
+	if dtoReq.DestinationBankName == "" {
+		dtoReq.DestinationBankName = bank.Name
+	}
+	return nil
+}
+
+// destination returns the bank of code if it may receive a transfer on rail.
+// A code missing from the registry only has its format checked and returns a
+// Bank without name: the list may be incomplete, BNI has the last word.
+func (r *Registry) destination(code string, rail Rail) (Bank, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return Bank{}, errors.NotValidf("empty destination bank code")
+	}
+	if !validCode(code) {
+		return Bank{}, errors.NotValidf("destination bank code %q, it must be 3 digits", code)
+	}
+	if code == BNICode {
+		return Bank{}, errors.NotValidf("destination bank code %s, transfers to BNI are in-house", code)
+	}
+
+	bank, ok := r.Lookup(code)
+	if !ok {
+		return Bank{Code: code}, nil
+	}
+	if !bank.Supports(rail) {
+		return Bank{}, errors.NotSupportedf("%s transfer to %s (%s)", rail, bank.Name, code)
+	}
+	return bank, nil
+}
@@ -0,0 +1,407 @@
+// Package batch sends many payments through a bni.Client with a bounded
+// number of workers and a request rate limit, and reports the outcome of
+// every item.
+//
+//	b := batch.New(client, batch.WithWorkers(4), batch.WithRateLimit(10), batch.WithCheckpoint("payroll.ckpt"))
+//	report, err := b.Run(ctx, instructions)
+//
+// With a checkpoint, each payment is marked in the file before it is sent and
+// its result appended once it finishes, and a Run restarted with the same
+// file skips finished items. An item marked but not finished, in flight when
+// the process died or ctx was cancelled, is never sent again: GetPaymentStatus
+// tells its outcome, and it is Unknown when BNI cannot tell.
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/status"
+	"github.com/juju/errors"
+)
+
+// Status is the outcome of one instruction.
+type Status string
+
+const (
+	// Success means BNI accepted the payment.
+	Success Status = "SUCCESS"
+	// Failed means the payment was not made: BNI rejected it or it was never sent.
+	Failed Status = "FAILED"
+	// Unknown means the payment was sent without a usable answer, check it with GetPaymentStatus.
+	Unknown Status = "UNKNOWN"
+
+	// sending marks, in the checkpoint, a payment about to be sent.
+	sending Status = "SENDING"
+)
+
+// Instruction is one payment. Exactly one of InHouse and InterBank is set.
+// For InterBank the inquiry is done by the batch, which fills RetrievalReffNum
+// and the destination names when empty.
+type Instruction struct {
+	ID        string
+	InHouse   *dto.DoPaymentRequest
+	InterBank *dto.GetInterBankPaymentRequest
+}
+
+func (i Instruction) crn() string {
+	switch {
+	case i.InHouse != nil:
+		return i.InHouse.CustomerReferenceNumber
+	case i.InterBank != nil:
+		return i.InterBank.CustomerReferenceNumber
+	}
+	return ""
+}
+
+// Result is the outcome of the instruction with the same ID.
+type Result struct {
+	ID              string    `json:"id"`
+	Status          Status    `json:"status"`
+	CRN             string    `json:"crn,omitempty"`
+	BankReference   string    `json:"bankReference,omitempty"`
+	ResponseCode    string    `json:"responseCode,omitempty"`
+	ResponseMessage string    `json:"responseMessage,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	FinishedAt      time.Time `json:"finishedAt"`
+}
+
+// Report holds one Result per instruction, in instruction order.
+type Report struct {
+	Results   []Result
+	Succeeded int
+	Failed    int
+	Unknown   int
+}
+
+type Option func(*Batch)
+
+// WithWorkers sets how many payments are in flight at once, 1 by default.
+func WithWorkers(n int) Option {
+	return func(b *Batch) {
+		if n > 0 {
+			b.workers = n
+		}
+	}
+}
+
+// WithRateLimit caps the requests sent to BNI per second, inquiries included.
+func WithRateLimit(perSecond float64) Option {
+	return func(b *Batch) {
+		if perSecond > 0 {
+			b.interval = time.Duration(float64(time.Second) / perSecond)
+		}
+	}
+}
+
+// WithCheckpoint records results to path and resumes from it, see the package doc.
+func WithCheckpoint(path string) Option {
+	return func(b *Batch) {
+		b.checkpointPath = path
+	}
+}
+
+// WithProgress calls fn after each item finishes, from the worker goroutines.
+func WithProgress(fn func(Result)) Option {
+	return func(b *Batch) {
+		b.progress = fn
+	}
+}
+
+// Batch runs instructions, it can be reused for several Runs.
+type Batch struct {
+	client         bni.Client
+	workers        int
+	interval       time.Duration
+	checkpointPath string
+	progress       func(Result)
+}
+
+func New(client bni.Client, opts ...Option) *Batch {
+	b := &Batch{client: client, workers: 1}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Run sends every instruction not already in the checkpoint and returns the
+// report. Payment failures are reported per item; the error is for invalid
+// instructions, checkpoint I/O and ctx cancellation, in which case the items
+// not yet run are missing from the checkpoint and Results.
+func (b *Batch) Run(ctx context.Context, instructions []Instruction) (*Report, error) {
+	if err := b.validate(instructions); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	done, interrupted := map[string]Result{}, map[string]Result{}
+	var ckpt *checkpoint
+	if b.checkpointPath != "" {
+		var err error
+		if ckpt, done, interrupted, err = openCheckpoint(b.checkpointPath); err != nil {
+			return nil, errors.Trace(err)
+		}
+		defer ckpt.Close()
+	}
+
+	limiter := newLimiter(b.interval)
+	defer limiter.stop()
+
+	results := make([]Result, len(instructions))
+	jobs := make(chan int)
+	var mutex sync.Mutex
+	var firstErr error
+
+	markSending := func(instruction Instruction) error {
+		if ckpt == nil {
+			return nil
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if firstErr != nil {
+			return firstErr
+		}
+		firstErr = ckpt.record(Result{ID: instruction.ID, Status: sending, CRN: instruction.crn()})
+		return firstErr
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < b.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var result Result
+				if _, ok := interrupted[instructions[i].ID]; ok {
+					result = b.resolve(ctx, limiter, instructions[i])
+				} else {
+					result = b.run(ctx, limiter, instructions[i], markSending)
+				}
+				if ctx.Err() != nil && result.Status != Success {
+					// cancelled mid-item: leave it for the next Run
+					continue
+				}
+
+				mutex.Lock()
+				results[i] = result
+				if ckpt != nil && firstErr == nil {
+					firstErr = ckpt.record(result)
+				}
+				mutex.Unlock()
+
+				if b.progress != nil {
+					b.progress(result)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i, instruction := range instructions {
+		if result, ok := done[instruction.ID]; ok {
+			results[i] = result
+			continue
+		}
+
+		mutex.Lock()
+		stop := firstErr != nil
+		mutex.Unlock()
+		if stop {
+			break
+		}
+
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, errors.Annotate(firstErr, "checkpoint")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newReport(results), nil
+}
+
+func (b *Batch) validate(instructions []Instruction) error {
+	ids := map[string]bool{}
+	for i, instruction := range instructions {
+		if instruction.ID == "" {
+			return errors.NotValidf("instruction %d without ID", i)
+		}
+		if ids[instruction.ID] {
+			return errors.NotValidf("duplicate instruction ID %q", instruction.ID)
+		}
+		ids[instruction.ID] = true
+
+		if (instruction.InHouse == nil) == (instruction.InterBank == nil) {
+			return errors.NotValidf("instruction %q must have exactly one of InHouse and InterBank", instruction.ID)
+		}
+		// a CRN generated during a run that crashes is lost, resuming would pay again
+		if b.checkpointPath != "" && instruction.crn() == "" {
+			return errors.NotValidf("instruction %q without CRN in a checkpointed batch", instruction.ID)
+		}
+	}
+	return nil
+}
+
+// run sends instruction, calling markSending right before the payment goes out.
+func (b *Batch) run(ctx context.Context, limiter *limiter, instruction Instruction, markSending func(Instruction) error) Result {
+	if instruction.InHouse != nil {
+		if err := limiter.wait(ctx); err != nil {
+			return finish(instruction, Failed, nil, err)
+		}
+		if err := markSending(instruction); err != nil {
+			return finish(instruction, Failed, nil, errors.Annotate(err, "checkpoint"))
+		}
+		resp, err := b.client.DoPayment(ctx, instruction.InHouse)
+		return finish(instruction, classify(err), resp, err)
+	}
+
+	// a copy, so a rerun of the same instructions does a fresh inquiry
+	req := *instruction.InterBank
+	instruction.InterBank = &req
+	if req.RetrievalReffNum == "" {
+		if err := limiter.wait(ctx); err != nil {
+			return finish(instruction, Failed, nil, err)
+		}
+		inquiry, err := b.client.GetInterBankInquiry(ctx, &dto.GetInterBankInquiryRequest{
+			AccountNum:            req.AccountNum,
+			DestinationBankCode:   req.DestinationBankCode,
+			DestinationAccountNum: req.DestinationAccountNum,
+		})
+		if err != nil {
+			// nothing was sent yet
+			return finish(instruction, Failed, nil, errors.Annotate(err, "inquiry"))
+		}
+
+		req.RetrievalReffNum = inquiry.Parameters.RetrievalReffNum.String()
+		if req.DestinationAccountName == "" {
+			req.DestinationAccountName = inquiry.Parameters.DestinationAccountName
+		}
+		if req.DestinationBankName == "" {
+			req.DestinationBankName = inquiry.Parameters.DestinationBankName
+		}
+	}
+
+	if err := limiter.wait(ctx); err != nil {
+		return finish(instruction, Failed, nil, err)
+	}
+	if err := markSending(instruction); err != nil {
+		return finish(instruction, Failed, nil, errors.Annotate(err, "checkpoint"))
+	}
+	resp, err := b.client.GetInterBankPayment(ctx, &req)
+	return finish(instruction, classify(err), resp, err)
+}
+
+// resolve asks BNI the outcome of a payment that was being sent when a
+// previous Run stopped, rather than sending it again.
+func (b *Batch) resolve(ctx context.Context, limiter *limiter, instruction Instruction) Result {
+	if err := limiter.wait(ctx); err != nil {
+		return finish(instruction, Unknown, nil, err)
+	}
+	resp, err := b.client.GetPaymentStatus(ctx, &dto.GetPaymentStatusRequest{CustomerReferenceNumber: instruction.crn()})
+	if err != nil {
+		return finish(instruction, Unknown, nil, errors.Annotate(err, "status of a payment interrupted by a previous run"))
+	}
+
+	switch status.FromResponse(resp) {
+	case status.Success:
+		return finish(instruction, Success, resp, nil)
+	case status.Failed:
+		return finish(instruction, Failed, resp, nil)
+	}
+	return finish(instruction, Unknown, resp, nil)
+}
+
+// classify tells a payment BNI refused, or that was never sent, from one with an unknown outcome.
+func classify(err error) Status {
+	switch errors.Cause(err) {
+	case nil:
+		return Success
+	case bni.BadResponseError, bni.ErrCRNConflict, bni.ErrProductionPaymentsDisabled:
+		return Failed
+	}
+	if errors.IsNotValid(err) {
+		return Failed
+	}
+	return Unknown
+}
+
+func finish(instruction Instruction, status Status, resp dto.Loggable, err error) Result {
+	result := Result{
+		ID:         instruction.ID,
+		Status:     status,
+		CRN:        instruction.crn(),
+		FinishedAt: time.Now().UTC(),
+	}
+	if resp != nil {
+		fields := resp.LogFields()
+		result.BankReference = fields.BankReference
+		result.ResponseCode = fields.RC
+		if responder, ok := resp.(dto.CommonResponder); ok {
+			result.ResponseMessage = responder.CommonParam().ResponseMessage
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		if responseErr, ok := bni.AsResponseError(err); ok {
+			result.ResponseCode = responseErr.ResponseCode
+			result.ResponseMessage = responseErr.ResponseMessage
+		}
+	}
+	return result
+}
+
+func newReport(results []Result) *Report {
+	report := &Report{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case Success:
+			report.Succeeded++
+		case Failed:
+			report.Failed++
+		case Unknown:
+			report.Unknown++
+		}
+	}
+	return report
+}
+
+// limiter lets one request through per interval, or all of them when interval is 0.
+type limiter struct {
+	ticker *time.Ticker
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	if interval <= 0 {
+		return &limiter{}
+	}
+	return &limiter{ticker: time.NewTicker(interval)}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
@@ -0,0 +1,236 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/bnifake"
+	"github.com/fundex-id/bni-api-mgmt/bnitest"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func inHouse(id, crn string) Instruction {
+	return Instruction{ID: id, InHouse: &dto.DoPaymentRequest{
+		CustomerReferenceNumber: crn,
+		DebitAccountNo:          "113183203",
+		CreditAccountNo:         "115471119",
+		ValueAmount:             "100500",
+		ValueCurrency:           "IDR",
+	}}
+}
+
+func paymentResponse(bankReference int64) *dto.DoPaymentResponse {
+	resp := &dto.DoPaymentResponse{}
+	resp.Parameters.ResponseCode = "0001"
+	resp.Parameters.ResponseMessage = "Request has been processed successfully"
+	resp.Parameters.BankReference = bankReference
+	return resp
+}
+
+func TestBatch_Run(t *testing.T) {
+	fake := bnifake.New()
+	fake.DoPaymentFunc = func(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error) {
+		switch dtoReq.CustomerReferenceNumber {
+		case "2":
+			return nil, errors.Trace(&bni.ResponseError{ResponseCode: "0104", ResponseMessage: "Insufficient balance"})
+		case "3":
+			return nil, errors.New("connection reset")
+		}
+		return paymentResponse(953354), nil
+	}
+	fake.GetInterBankInquiryFunc = func(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error) {
+		resp := &dto.GetInterBankInquiryResponse{}
+		resp.Parameters.RetrievalReffNum = "100000000097"
+		resp.Parameters.DestinationAccountName = "BENEFICIARY NAME"
+		return resp, nil
+	}
+	var interBankReq dto.GetInterBankPaymentRequest
+	fake.GetInterBankPaymentFunc = func(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (*dto.GetInterBankPaymentResponse, error) {
+		interBankReq = *dtoReq
+		return &dto.GetInterBankPaymentResponse{}, nil
+	}
+
+	interBank := &dto.GetInterBankPaymentRequest{
+		CustomerReferenceNumber: "4",
+		AccountNum:              "113183203",
+		DestinationBankCode:     "014",
+		DestinationAccountNum:   "3333333333",
+		Amount:                  "100500",
+	}
+	instructions := []Instruction{
+		inHouse("a", "1"),
+		inHouse("b", "2"),
+		inHouse("c", "3"),
+		{ID: "d", InterBank: interBank},
+	}
+
+	var progressed int32
+	report, err := New(fake, WithWorkers(3), WithProgress(func(Result) {
+		atomic.AddInt32(&progressed, 1)
+	})).Run(context.Background(), instructions)
+	if !util.AssertErrNil(t, err) {
+		return
+	}
+
+	assert.Equal(t, int32(4), progressed)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Unknown)
+
+	var statuses []Status
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []Status{Success, Failed, Unknown, Success}, statuses)
+
+	assert.Equal(t, "953354", report.Results[0].BankReference)
+	assert.Equal(t, "0001", report.Results[0].ResponseCode)
+	assert.Equal(t, "0104", report.Results[1].ResponseCode)
+	assert.Equal(t, "Insufficient balance", report.Results[1].ResponseMessage)
+	assert.Equal(t, "connection reset", report.Results[2].Error)
+
+	assert.Equal(t, "100000000097", interBankReq.RetrievalReffNum)
+	assert.Equal(t, "BENEFICIARY NAME", interBankReq.DestinationAccountName)
+	assert.Empty(t, interBank.RetrievalReffNum, "the caller's request is left untouched")
+}
+
+// TestBatch_RunConcurrentClient shares one real client between workers, run
+// it with -race.
+func TestBatch_RunConcurrentClient(t *testing.T) {
+	srv := bnitest.NewServer(
+		bnitest.WithAccount(bnitest.Account{Number: "113183203", Name: "FUNDEX", Balance: 10000000}),
+		bnitest.WithAccount(bnitest.Account{Number: "115471119", Name: "BENEFICIARY"}),
+	)
+	defer srv.Close()
+
+	cfg := srv.Config()
+	cfg.PrivateKeyPath = "../testdata/id_rsa.pem"
+	client := bni.New(cfg)
+
+	var instructions []Instruction
+	for i := 0; i < 16; i++ {
+		crn := fmt.Sprintf("201702270000000000%02d", i)
+		instructions = append(instructions, inHouse(crn, crn))
+	}
+
+	report, err := New(client, WithWorkers(8)).Run(context.Background(), instructions)
+	if util.AssertErrNil(t, err) {
+		assert.Equal(t, 16, report.Succeeded)
+	}
+	balance, _ := srv.Balance("113183203")
+	assert.Equal(t, int64(10000000-16*100500), balance)
+}
+
+func TestBatch_Validate(t *testing.T) {
+	b := New(bnifake.New())
+
+	_, err := b.Run(context.Background(), []Instruction{inHouse("a", "1"), inHouse("a", "2")})
+	assert.True(t, errors.IsNotValid(err))
+
+	_, err = b.Run(context.Background(), []Instruction{{ID: "a"}})
+	assert.True(t, errors.IsNotValid(err))
+
+	_, err = New(bnifake.New(), WithCheckpoint(filepath.Join(t.TempDir(), "ckpt"))).
+		Run(context.Background(), []Instruction{inHouse("a", "")})
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestBatch_Checkpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ckpt")
+
+	var calls int32
+	fake := bnifake.New()
+	fake.DoPaymentFunc = func(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		return paymentResponse(1), nil
+	}
+	instructions := []Instruction{inHouse("a", "1"), inHouse("b", "2")}
+
+	_, err := New(fake, WithCheckpoint(path)).Run(context.Background(), instructions[:1])
+	util.AssertErrNil(t, err)
+
+	// a record torn by a crash is dropped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if util.AssertErrNil(t, err) {
+		file.WriteString(`{"id":"b","sta`)
+		file.Close()
+	}
+
+	report, err := New(fake, WithCheckpoint(path)).Run(context.Background(), instructions)
+	if !util.AssertErrNil(t, err) {
+		return
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, 2, report.Succeeded)
+
+	content, err := ioutil.ReadFile(path)
+	util.AssertErrNil(t, err)
+	var ids []string
+	for _, line := range splitLines(content) {
+		var result Result
+		util.AssertErrNil(t, json.Unmarshal(line, &result))
+		ids = append(ids, result.ID+" "+string(result.Status))
+	}
+	assert.Equal(t, []string{"a SENDING", "a SUCCESS", "b SENDING", "b SUCCESS"}, ids)
+
+	_, err = New(fake, WithCheckpoint(path)).Run(context.Background(), instructions)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "finished items are not sent again")
+}
+
+func TestBatch_CheckpointInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ckpt")
+	util.AssertErrNil(t, ioutil.WriteFile(path, []byte(
+		`{"id":"a","status":"SENDING","crn":"1"}`+"\n"+
+			`{"id":"b","status":"SENDING","crn":"2"}`+"\n"+
+			`{"id":"c","status":"SENDING","crn":"3"}`+"\n"), 0600))
+
+	fake := bnifake.New()
+	fake.GetPaymentStatusFunc = func(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error) {
+		resp := &dto.GetPaymentStatusResponse{}
+		switch dtoReq.CustomerReferenceNumber {
+		case "1":
+			resp.Parameters.PreviousResponse.TransactionStatus = "Y"
+		case "2":
+			resp.Parameters.PreviousResponse.TransactionStatus = "N"
+		default:
+			return nil, bni.BadResponseError
+		}
+		return resp, nil
+	}
+
+	report, err := New(fake, WithCheckpoint(path)).
+		Run(context.Background(), []Instruction{inHouse("a", "1"), inHouse("b", "2"), inHouse("c", "3")})
+	if !util.AssertErrNil(t, err) {
+		return
+	}
+
+	var statuses []Status
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []Status{Success, Failed, Unknown}, statuses, "BNI not knowing the CRN is not a failure")
+	assert.Empty(t, fake.CallsTo(bni.DoPaymentOperation), "interrupted payments are never sent again")
+}
+
+func splitLines(content []byte) [][]byte {
+	var lines [][]byte
+	start := 0
+	for i, c := range content {
+		if c == '\n' {
+			lines = append(lines, content[start:i])
+			start = i + 1
+		}
+	}
+	return lines
+}
@@ -0,0 +1,81 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/juju/errors"
+)
+
+// checkpoint is a JSON-lines file of Results, synced after each line: a
+// sending marker when a payment goes out, then its final Result.
+type checkpoint struct {
+	file *os.File
+}
+
+// openCheckpoint reads the results already in path and opens it for
+// appending. interrupted holds the items marked sending without a final
+// Result. A truncated last line, from a crash mid-write, is ignored.
+func openCheckpoint(path string) (ckpt *checkpoint, done, interrupted map[string]Result, err error) {
+	done, interrupted = map[string]Result{}, map[string]Result{}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, nil, errors.Trace(err)
+	}
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, errors.Trace(err)
+	}
+
+	// only lines ending with a newline were fully written
+	var valid int64
+	for {
+		end := bytes.IndexByte(content[valid:], '\n')
+		if end < 0 {
+			break
+		}
+		var result Result
+		if err := json.Unmarshal(content[valid:valid+int64(end)], &result); err != nil {
+			break
+		}
+		if result.Status == sending {
+			interrupted[result.ID] = result
+		} else {
+			done[result.ID] = result
+			delete(interrupted, result.ID)
+		}
+		valid += int64(end) + 1
+	}
+
+	// drop the torn line so new records start on a clean line
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, nil, nil, errors.Trace(err)
+	}
+	if _, err := file.Seek(valid, 0); err != nil {
+		file.Close()
+		return nil, nil, nil, errors.Trace(err)
+	}
+
+	return &checkpoint{file: file}, done, interrupted, nil
+}
+
+func (c *checkpoint) record(result Result) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.file.Sync())
+}
+
+func (c *checkpoint) Close() error {
+	return c.file.Close()
+}
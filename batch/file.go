@@ -0,0 +1,462 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Field is a payment attribute a file column can hold.
+type Field string
+
+const (
+	FieldID              Field = "id"
+	FieldType            Field = "type"
+	FieldCRN             Field = "crn"
+	FieldDebitAccount    Field = "debitAccount"
+	FieldCreditAccount   Field = "creditAccount"
+	FieldAmount          Field = "amount"
+	FieldCurrency        Field = "currency"
+	FieldPaymentMethod   Field = "paymentMethod"
+	FieldBankCode        Field = "bankCode"
+	FieldBankName        Field = "bankName"
+	FieldBeneficiaryName Field = "beneficiaryName"
+	FieldEmail           Field = "email"
+	FieldRemark          Field = "remark"
+)
+
+var fields = []Field{
+	FieldID, FieldType, FieldCRN, FieldDebitAccount, FieldCreditAccount, FieldAmount, FieldCurrency,
+	FieldPaymentMethod, FieldBankCode, FieldBankName, FieldBeneficiaryName, FieldEmail, FieldRemark,
+}
+
+// Values of the type column.
+const (
+	TypeInHouse   = "inhouse"
+	TypeInterBank = "interbank"
+)
+
+// Mapping names the file column holding each field, columns are matched
+// case-insensitively. A field left out is not read.
+type Mapping map[Field]string
+
+// DefaultMapping reads every field from the column of the same name.
+func DefaultMapping() Mapping {
+	mapping := Mapping{}
+	for _, field := range fields {
+		mapping[field] = string(field)
+	}
+	return mapping
+}
+
+// LoadMapping reads a YAML (.yaml, .yml) or JSON (.json) object of field to
+// column name, on top of DefaultMapping. Mapping a field to "" stops it being read.
+func LoadMapping(path string) (Mapping, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var fileMapping Mapping
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(content, &fileMapping)
+	case ".json":
+		err = json.Unmarshal(content, &fileMapping)
+	default:
+		return nil, errors.NotSupportedf("mapping file extension %q", ext)
+	}
+	if err != nil {
+		return nil, errors.Annotate(err, path)
+	}
+
+	mapping := DefaultMapping()
+	for field, column := range fileMapping {
+		if _, ok := mapping[field]; !ok {
+			return nil, errors.NotValidf("field %q in %s", field, path)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// RowError is a problem with one row. Row counts from 1 at the first line of
+// a CSV file, the header, and at the first element of a JSON array.
+type RowError struct {
+	Row     int
+	Column  string
+	Message string
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s: %s", e.Row, e.Column, e.Message)
+}
+
+// RowErrors lists every invalid row of a file.
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	const shown = 5
+	messages := make([]string, 0, shown)
+	for i, rowErr := range e {
+		if i == shown {
+			messages = append(messages, fmt.Sprintf("and %d more", len(e)-shown))
+			break
+		}
+		messages = append(messages, rowErr.Error())
+	}
+	return fmt.Sprintf("%d invalid rows: %s", len(e), strings.Join(messages, "; "))
+}
+
+// ReadCSV reads instructions from a CSV file with a header line. When rows
+// are invalid the error is a RowErrors, returned along with the valid rows.
+//
+// A row is an InterBank payment when its type column says so, or when it has
+// no type column but a bank code without a payment method; otherwise InHouse.
+// The ID defaults to the CRN.
+func ReadCSV(r io.Reader, mapping Mapping) ([]Instruction, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Annotate(err, "csv header")
+	}
+	columns, err := mapping.columns(header)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	parser := newRowParser(mapping)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Annotatef(err, "csv row %d", row)
+		}
+
+		values := map[Field]string{}
+		for field, index := range columns {
+			values[field] = record[index]
+		}
+		parser.parse(row, values)
+	}
+	return parser.result()
+}
+
+// ReadJSON reads instructions from a JSON array of objects, keyed by the
+// mapped column names, with string or number values. Errors are as for ReadCSV.
+func ReadJSON(r io.Reader, mapping Mapping) ([]Instruction, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, errors.Annotate(err, "json")
+	}
+
+	parser := newRowParser(mapping)
+	for i, object := range objects {
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		columns, err := mapping.columns(keys)
+		if err != nil {
+			parser.errs = append(parser.errs, RowError{Row: i + 1, Message: err.Error()})
+			continue
+		}
+
+		values := map[Field]string{}
+		valid := true
+		for field, index := range columns {
+			switch value := object[keys[index]].(type) {
+			case string:
+				values[field] = value
+			case json.Number:
+				values[field] = value.String()
+			case nil:
+			default:
+				parser.errs = append(parser.errs, RowError{Row: i + 1, Column: keys[index], Message: "not a string or number"})
+				valid = false
+			}
+		}
+		if valid {
+			parser.parse(i+1, values)
+		}
+	}
+	return parser.result()
+}
+
+// ReadFile reads a .csv or .json file, see ReadCSV and ReadJSON.
+func ReadFile(path string, mapping Mapping) ([]Instruction, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return ReadCSV(bytes.NewReader(content), mapping)
+	case ".json":
+		return ReadJSON(bytes.NewReader(content), mapping)
+	default:
+		return nil, errors.NotSupportedf("batch file extension %q", ext)
+	}
+}
+
+// columns finds the index in names of each mapped field. The debit account,
+// credit account and amount columns are required.
+func (m Mapping) columns(names []string) (map[Field]int, error) {
+	byName := map[string]int{}
+	for i, name := range names {
+		byName[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := map[Field]int{}
+	var missing []string
+	for _, field := range fields {
+		column := m[field]
+		if column == "" {
+			continue
+		}
+		if index, ok := byName[strings.ToLower(column)]; ok {
+			columns[field] = index
+			continue
+		}
+		switch field {
+		case FieldDebitAccount, FieldCreditAccount, FieldAmount:
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.NotFoundf("columns %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+var (
+	digits = regexp.MustCompile(`^[0-9]+$`)
+	amount = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,2})?$`)
+)
+
+// rowParser turns rows into instructions and collects the errors of all rows.
+type rowParser struct {
+	mapping      Mapping
+	instructions []Instruction
+	errs         RowErrors
+	ids          map[string]int
+}
+
+func newRowParser(mapping Mapping) *rowParser {
+	return &rowParser{mapping: mapping, ids: map[string]int{}}
+}
+
+func (p *rowParser) parse(row int, values map[Field]string) {
+	for field, value := range values {
+		values[field] = strings.TrimSpace(value)
+	}
+	errCount := len(p.errs)
+	fail := func(field Field, message string, args ...interface{}) {
+		p.errs = append(p.errs, RowError{Row: row, Column: p.mapping[field], Message: fmt.Sprintf(message, args...)})
+	}
+
+	// skip blank lines spreadsheets leave at the end
+	blank := true
+	for _, value := range values {
+		if value != "" {
+			blank = false
+		}
+	}
+	if blank {
+		return
+	}
+
+	id := values[FieldID]
+	if id == "" {
+		id = values[FieldCRN]
+	}
+	switch previous, seen := p.ids[id]; {
+	case id == "":
+		fail(FieldID, "missing, and no CRN to use instead")
+	case seen:
+		fail(FieldID, "%q already used on row %d", id, previous)
+	default:
+		p.ids[id] = row
+	}
+
+	for _, field := range []Field{FieldDebitAccount, FieldCreditAccount} {
+		if !digits.MatchString(values[field]) {
+			fail(field, "%q is not an account number", values[field])
+		}
+	}
+	if !amount.MatchString(values[FieldAmount]) || strings.Trim(values[FieldAmount], "0.") == "" {
+		fail(FieldAmount, "%q is not a positive amount", values[FieldAmount])
+	}
+	if crn := values[FieldCRN]; len(crn) > 20 {
+		fail(FieldCRN, "%q is longer than 20 characters", crn)
+	}
+	if code := values[FieldBankCode]; code != "" && !digits.MatchString(code) {
+		fail(FieldBankCode, "%q is not a bank code", code)
+	}
+
+	kind := strings.ToLower(values[FieldType])
+	switch kind {
+	case "":
+		kind = TypeInHouse
+		if values[FieldBankCode] != "" && values[FieldPaymentMethod] == "" {
+			kind = TypeInterBank
+		}
+	case TypeInHouse:
+	case TypeInterBank:
+		if values[FieldBankCode] == "" {
+			fail(FieldBankCode, "missing for an interbank payment")
+		}
+	default:
+		fail(FieldType, "%q is neither %s nor %s", values[FieldType], TypeInHouse, TypeInterBank)
+	}
+	if currency := values[FieldCurrency]; kind == TypeInterBank && currency != "" && currency != "IDR" {
+		fail(FieldCurrency, "interbank payments are in IDR only")
+	}
+
+	if len(p.errs) > errCount {
+		return
+	}
+
+	instruction := Instruction{ID: id}
+	if kind == TypeInterBank {
+		instruction.InterBank = &dto.GetInterBankPaymentRequest{
+			CustomerReferenceNumber: values[FieldCRN],
+			AccountNum:              values[FieldDebitAccount],
+			DestinationAccountNum:   values[FieldCreditAccount],
+			DestinationAccountName:  values[FieldBeneficiaryName],
+			DestinationBankCode:     values[FieldBankCode],
+			DestinationBankName:     values[FieldBankName],
+			Amount:                  values[FieldAmount],
+		}
+	} else {
+		currency := values[FieldCurrency]
+		if currency == "" {
+			currency = "IDR"
+		}
+		instruction.InHouse = &dto.DoPaymentRequest{
+			CustomerReferenceNumber: values[FieldCRN],
+			PaymentMethod:           values[FieldPaymentMethod],
+			DebitAccountNo:          values[FieldDebitAccount],
+			CreditAccountNo:         values[FieldCreditAccount],
+			ValueAmount:             values[FieldAmount],
+			ValueCurrency:           currency,
+			Remark:                  values[FieldRemark],
+			BeneficiaryEmailAddress: values[FieldEmail],
+			DestinationBankCode:     values[FieldBankCode],
+			BeneficiaryName:         values[FieldBeneficiaryName],
+		}
+	}
+	p.instructions = append(p.instructions, instruction)
+}
+
+func (p *rowParser) result() ([]Instruction, error) {
+	if len(p.errs) > 0 {
+		return p.instructions, p.errs
+	}
+	return p.instructions, nil
+}
+
+// resultColumns is the header of WriteCSV.
+var resultColumns = []string{"id", "crn", "status", "bank_reference", "response_code", "response_message", "error", "finished_at"}
+
+// WriteCSV writes one line per result, in report order, under a header line.
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(resultColumns); err != nil {
+		return errors.Trace(err)
+	}
+	for _, result := range report.Results {
+		finishedAt := ""
+		if !result.FinishedAt.IsZero() {
+			finishedAt = result.FinishedAt.Format(time.RFC3339)
+		}
+		record := []string{
+			result.ID, result.CRN, string(result.Status), result.BankReference,
+			result.ResponseCode, result.ResponseMessage, result.Error, finishedAt,
+		}
+		if err := writer.Write(record); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	writer.Flush()
+	return errors.Trace(writer.Error())
+}
+
+// WriteJSON writes the results as an indented JSON array, in report order.
+func WriteJSON(w io.Writer, report *Report) error {
+	results := report.Results
+	if results == nil {
+		results = []Result{}
+	}
+	raw, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = w.Write(append(raw, '\n'))
+	return errors.Trace(err)
+}
+
+// WriteFile writes the results to a .csv or .json file, see WriteCSV and WriteJSON.
+func WriteFile(path string, report *Report) error {
+	var buf bytes.Buffer
+	var err error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		err = WriteCSV(&buf, report)
+	case ".json":
+		err = WriteJSON(&buf, report)
+	default:
+		return errors.NotSupportedf("results file extension %q", ext)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(path, buf.Bytes(), 0600))
+}
+
+// Total adds up the amounts of instructions, in the smallest currency unit.
+func Total(instructions []Instruction) (int64, error) {
+	var total int64
+	for _, instruction := range instructions {
+		value := ""
+		switch {
+		case instruction.InHouse != nil:
+			value = instruction.InHouse.ValueAmount
+		case instruction.InterBank != nil:
+			value = instruction.InterBank.Amount
+		}
+
+		whole, fraction := value, "00"
+		if dot := strings.IndexByte(value, '.'); dot >= 0 {
+			whole, fraction = value[:dot], (value[dot+1:] + "00")[:2]
+		}
+		units, err := strconv.ParseInt(whole+fraction, 10, 64)
+		if err != nil {
+			return 0, errors.NotValidf("amount %q of instruction %q", value, instruction.ID)
+		}
+		total += units
+	}
+	return total, nil
+}
@@ -0,0 +1,137 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCSV(t *testing.T) {
+	file := `No,Reference,From,To,Nominal,Bank,Name
+1,20170227000000000020,113183203,115471119,100500,,
+2,20170227000000000021,113183203,3333333333,250000.50,014,BENEFICIARY NAME
+3,20170227000000000022,113183203,abc,0,,
+1,20170227000000000023,113183203,115471119,1,XYZ,
+,,,,,,
+`
+	mapping := DefaultMapping()
+	mapping[FieldID] = "no"
+	mapping[FieldCRN] = "Reference"
+	mapping[FieldDebitAccount] = "From"
+	mapping[FieldCreditAccount] = "To"
+	mapping[FieldAmount] = "Nominal"
+	mapping[FieldBankCode] = "Bank"
+	mapping[FieldBeneficiaryName] = "Name"
+
+	instructions, err := ReadCSV(strings.NewReader(file), mapping)
+	rowErrs, ok := errors.Cause(err).(RowErrors)
+	if !assert.True(t, ok, "%v", err) {
+		return
+	}
+
+	var messages []string
+	for _, rowErr := range rowErrs {
+		messages = append(messages, rowErr.Error())
+	}
+	assert.Equal(t, []string{
+		`row 4: To: "abc" is not an account number`,
+		`row 4: Nominal: "0" is not a positive amount`,
+		`row 5: no: "1" already used on row 2`,
+		`row 5: Bank: "XYZ" is not a bank code`,
+	}, messages)
+
+	if assert.Len(t, instructions, 2) {
+		assert.Equal(t, "1", instructions[0].ID)
+		if assert.NotNil(t, instructions[0].InHouse) {
+			assert.Equal(t, "100500", instructions[0].InHouse.ValueAmount)
+			assert.Equal(t, "IDR", instructions[0].InHouse.ValueCurrency)
+		}
+		if assert.NotNil(t, instructions[1].InterBank) {
+			assert.Equal(t, "014", instructions[1].InterBank.DestinationBankCode)
+			assert.Equal(t, "3333333333", instructions[1].InterBank.DestinationAccountNum)
+			assert.Equal(t, "BENEFICIARY NAME", instructions[1].InterBank.DestinationAccountName)
+		}
+	}
+
+	_, err = ReadCSV(strings.NewReader("crn,amount\n1,2\n"), DefaultMapping())
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestReadJSON(t *testing.T) {
+	file := `[
+		{"crn": "20170227000000000020", "debitAccount": "113183203", "creditAccount": 115471119, "amount": 100500, "type": "inhouse", "bankCode": "014", "paymentMethod": "1"},
+		{"crn": "20170227000000000021", "debitAccount": "113183203", "creditAccount": "3333333333", "amount": "1", "type": "interbank"},
+		{"crn": "20170227000000000022", "debitAccount": "113183203", "creditAccount": "3333333333", "amount": true}
+	]`
+
+	instructions, err := ReadJSON(strings.NewReader(file), DefaultMapping())
+	rowErrs, ok := errors.Cause(err).(RowErrors)
+	if assert.True(t, ok, "%v", err) {
+		assert.Equal(t, RowErrors{
+			{Row: 2, Column: "bankCode", Message: "missing for an interbank payment"},
+			{Row: 3, Column: "amount", Message: "not a string or number"},
+		}, rowErrs)
+	}
+
+	if assert.Len(t, instructions, 1) && assert.NotNil(t, instructions[0].InHouse) {
+		assert.Equal(t, "20170227000000000020", instructions[0].ID)
+		assert.Equal(t, "115471119", instructions[0].InHouse.CreditAccountNo)
+		assert.Equal(t, "1", instructions[0].InHouse.PaymentMethod)
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mapping.yaml")
+	util.AssertErrNil(t, ioutil.WriteFile(path, []byte("amount: Nominal\nremark: \"\"\n"), 0600))
+
+	mapping, err := LoadMapping(path)
+	if util.AssertErrNil(t, err) {
+		assert.Equal(t, "Nominal", mapping[FieldAmount])
+		assert.Equal(t, "", mapping[FieldRemark])
+		assert.Equal(t, "crn", mapping[FieldCRN])
+	}
+
+	path = filepath.Join(dir, "mapping.json")
+	util.AssertErrNil(t, ioutil.WriteFile(path, []byte(`{"nominal": "Nominal"}`), 0600))
+	_, err = LoadMapping(path)
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := newReport([]Result{
+		{ID: "1", Status: Success, CRN: "20170227000000000020", BankReference: "953354", ResponseCode: "0001",
+			ResponseMessage: "Request has been processed successfully", FinishedAt: time.Date(2017, 2, 27, 10, 0, 0, 0, time.UTC)},
+		{ID: "2", Status: Unknown, Error: "connection reset, by peer"},
+	})
+
+	var buf bytes.Buffer
+	util.AssertErrNil(t, WriteCSV(&buf, report))
+	assert.Equal(t, `id,crn,status,bank_reference,response_code,response_message,error,finished_at
+1,20170227000000000020,SUCCESS,953354,0001,Request has been processed successfully,,2017-02-27T10:00:00Z
+2,,UNKNOWN,,,,"connection reset, by peer",
+`, buf.String())
+
+	buf.Reset()
+	util.AssertErrNil(t, WriteJSON(&buf, report))
+	var results []Result
+	util.AssertErrNil(t, json.Unmarshal(buf.Bytes(), &results))
+	assert.Equal(t, report.Results, results)
+}
+
+func TestTotal(t *testing.T) {
+	instructions, err := ReadCSV(strings.NewReader("crn,debitAccount,creditAccount,amount\n1,1,2,100500\n2,1,2,0.5\n3,1,2,10.25\n"), DefaultMapping())
+	util.AssertErrNil(t, err)
+
+	total, err := Total(instructions)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, int64(10050000+50+1025), total)
+}
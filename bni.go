@@ -2,64 +2,93 @@ package bni
 
 import (
 	"context"
-	"os"
+	"time"
 
 	"github.com/fundex-id/bni-api-mgmt/config"
 	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
 	"github.com/fundex-id/bni-api-mgmt/dto"
 	"github.com/fundex-id/bni-api-mgmt/logger"
+	"github.com/fundex-id/bni-api-mgmt/metrics"
+	"github.com/fundex-id/bni-api-mgmt/redact"
 	"github.com/fundex-id/bni-api-mgmt/signature"
 	"github.com/juju/errors"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var BadResponseError error = errors.New("Bad response")
 
+// ErrProductionPaymentsDisabled is returned by money-moving operations sent to
+// production without config.AllowProductionPayments.
+var ErrProductionPaymentsDisabled = errors.New("payments to BNI production are not enabled")
+
+// logMsgKey is the field holding the dto.LogMsg of each request and response.
+const logMsgKey = "bniLog"
+
+// BNI is a bni.Client calling the BNI H2H API, safe for concurrent use.
 type BNI struct {
 	api       *API
 	config    config.Config
 	signature *signature.Signature
+	metrics   metrics.Recorder
+	tracer    trace.Tracer
+	logger    *zap.SugaredLogger
+	redactor  *redact.Redactor
+	auditSink AuditSink
+	ledger    Ledger
+	crnGen    CRNGenerator
+	validator RequestValidator
 }
 
-func New(config config.Config) *BNI {
+// New builds the client of config. A config with an invalid LogLevel logs at
+// info after a warning; config.Validate and Registry.Add reject it.
+func New(config config.Config, opts ...Option) *BNI {
+	bni, err := newBNI(config, opts...)
+	if err != nil {
+		bni.logger.Warnf("%s, logging at info", err)
+	}
+	return bni
+}
+
+// newBNI is New returning the config problems New only logs.
+func newBNI(config config.Config, opts ...Option) (*BNI, error) {
 	bni := BNI{
 		config:    config,
 		api:       newApi(config),
 		signature: signature.New(config.SignatureConfig),
+		metrics:   metrics.Nop{},
+		tracer:    defaultTracer(),
+		redactor:  defaultRedactor(),
+		auditSink: nopAuditSink{},
+		ledger:    nopLedger{},
+	}
+	for _, opt := range opts {
+		opt(&bni)
+	}
+	var err error
+	if bni.logger == nil {
+		var configLogger *zap.Logger
+		configLogger, err = newConfigLogger(config)
+		bni.logger = configLogger.Sugar()
 	}
 
-	logger.SetOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-
-		fileWriteSyncer := zapcore.AddSync(&lumberjack.Logger{
-			Filename: config.LogPath,
-			MaxSize:  500, // megabytes
-			// MaxBackups: 3,
-			// MaxAge:     28, // days
-		})
-		stdoutWriteSyncer := zapcore.AddSync(os.Stdout)
-
-		return zapcore.NewCore(
-			zapcore.NewJSONEncoder(logger.DefaultEncoderConfig),
-			zapcore.NewMultiWriteSyncer(fileWriteSyncer, stdoutWriteSyncer),
-			zap.InfoLevel,
-		)
-
-		// return core
-	}))
+	bni.api.metrics = bni.metrics
+	bni.api.tracer = bni.tracer
+	bni.api.logger = bni.logger
+	bni.api.redactor = bni.redactor
 
-	return &bni
+	return &bni, err
 }
 
 // === APi based on spec ===
 
-func (b *BNI) DoAuthentication(ctx context.Context) (*dto.GetTokenResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) DoAuthentication(ctx context.Context) (dtoResp *dto.GetTokenResponse, err error) {
+	ctx, end := b.begin(ctx, DoAuthOperation, nil)
+	defer func() { end(nil, err) }()
 
 	b.log(ctx).Info("=== DO_AUTH ===")
 
-	dtoResp, err := b.api.doAuthentication(ctx)
+	dtoResp, err = b.api.doAuthentication(ctx)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
@@ -70,33 +99,36 @@ func (b *BNI) DoAuthentication(ctx context.Context) (*dto.GetTokenResponse, erro
 	return dtoResp, nil
 }
 
-func (b *BNI) GetBalance(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*dto.GetBalanceResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) GetBalance(ctx context.Context, dtoReq *dto.GetBalanceRequest) (dtoParamResp *dto.GetBalanceResponse, err error) {
+	ctx, end := b.begin(ctx, GetBalanceOperation, dtoReq)
+	var dtoResp *dto.ApiResponse
+	defer func() { end(dtoResp, err) }()
 
 	b.log(ctx).Info("=== GET_BALANCE ===")
 
 	dtoReq.ClientID = b.config.ClientID
-	if err := b.setSignatureGetBalance(dtoReq); err != nil {
+	if err := b.setSignature(ctx, dtoReq); err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logReq := dto.BuildLogRequest(BalanceRequest, dtoReq)
-	b.log(ctx).Infof("%+v", logReq)
+	logReq := dto.BuildLogRequest(BalanceRequest, dtoReq, b.redactor)
+	b.log(ctx).Infow(logReq.Operation, logMsgKey, logReq)
 
-	dtoResp, err := b.api.postGetBalance(ctx, dtoReq)
+	dtoResp, err = b.api.postGetBalance(ctx, dtoReq)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logResp := dto.BuildLogResponse(BalanceResponse, dtoResp)
-	b.log(ctx).Infof("%+v", logResp)
+	logResp := dto.BuildLogResponse(BalanceResponse, dtoResp, b.redactor)
+	b.log(ctx).Infow(logResp.Operation, logMsgKey, logResp)
 
-	dtoParamResp := dtoResp.GetBalanceResponse
+	dtoParamResp = dtoResp.GetBalanceResponse
 	if dtoParamResp == nil {
-		b.log(ctx).Error(BadResponseError)
-		return nil, BadResponseError
+		responseErr := newResponseError(dtoResp)
+		b.log(ctx).Error(responseErr)
+		return nil, responseErr
 	}
 
 	b.log(ctx).Info("=== END GET_BALANCE ===")
@@ -104,33 +136,36 @@ func (b *BNI) GetBalance(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*d
 	return dtoParamResp, nil
 }
 
-func (b *BNI) GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (dtoParamResp *dto.GetInHouseInquiryResponse, err error) {
+	ctx, end := b.begin(ctx, GetInHouseInquiryOperation, dtoReq)
+	var dtoResp *dto.ApiResponse
+	defer func() { end(dtoResp, err) }()
 
 	b.log(ctx).Info("=== GET_IN_HOUSE_INQUIRY ===")
 
 	dtoReq.ClientID = b.config.ClientID
-	if err := b.setSignatureGetInHouseInquiry(dtoReq); err != nil {
+	if err := b.setSignature(ctx, dtoReq); err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logReq := dto.BuildLogRequest(InHouseInquiryRequest, dtoReq)
-	b.log(ctx).Infof("%+v", logReq)
+	logReq := dto.BuildLogRequest(InHouseInquiryRequest, dtoReq, b.redactor)
+	b.log(ctx).Infow(logReq.Operation, logMsgKey, logReq)
 
-	dtoResp, err := b.api.postGetInHouseInquiry(ctx, dtoReq)
+	dtoResp, err = b.api.postGetInHouseInquiry(ctx, dtoReq)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logResp := dto.BuildLogResponse(InHouseInquiryResponse, dtoResp)
-	b.log(ctx).Infof("%+v", logResp)
+	logResp := dto.BuildLogResponse(InHouseInquiryResponse, dtoResp, b.redactor)
+	b.log(ctx).Infow(logResp.Operation, logMsgKey, logResp)
 
-	dtoParamResp := dtoResp.GetInHouseInquiryResponse
+	dtoParamResp = dtoResp.GetInHouseInquiryResponse
 	if dtoParamResp == nil {
-		b.log(ctx).Error(BadResponseError)
-		return nil, BadResponseError
+		responseErr := newResponseError(dtoResp)
+		b.log(ctx).Error(responseErr)
+		return nil, responseErr
 	}
 
 	b.log(ctx).Info("=== END GET_IN_HOUSE_INQUIRY ===")
@@ -138,33 +173,79 @@ func (b *BNI) GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInqui
 	return dtoParamResp, nil
 }
 
-func (b *BNI) DoPayment(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) DoPayment(ctx context.Context, dtoReq *dto.DoPaymentRequest) (dtoParamResp *dto.DoPaymentResponse, err error) {
+	ctx, end := b.begin(ctx, DoPaymentOperation, dtoReq)
+	var dtoResp *dto.ApiResponse
+	defer func() { end(dtoResp, err) }()
 
 	b.log(ctx).Info("=== DO_PAYMENT ===")
 
+	if err := b.checkPaymentsAllowed(); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	if err := b.fillCRN(ctx, &dtoReq.CustomerReferenceNumber); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	if err := b.validate(dtoReq); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
 	dtoReq.ClientID = b.config.ClientID
-	if err := b.setSignatureDoPayment(dtoReq); err != nil {
+	if err := b.setSignature(ctx, dtoReq); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	hash, err := payloadHash(dtoReq)
+	if err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	stored, err := b.reserveCRN(ctx, dtoReq.CustomerReferenceNumber, hash)
+	if err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+	if stored != nil {
+		b.log(ctx).Infof("CRN %s already %s, returning the stored result", dtoReq.CustomerReferenceNumber, stored.State)
+		dtoParamResp = &dto.DoPaymentResponse{}
+		if err := stored.replay(dtoParamResp); err != nil {
+			return nil, err
+		}
+		return dtoParamResp, nil
+	}
+
+	if err := b.auditInitiated(ctx, dtoReq.CustomerReferenceNumber, hash); err != nil {
 		b.log(ctx).Error(errors.Details(err))
+		b.releaseCRN(ctx, dtoReq.CustomerReferenceNumber)
 		return nil, errors.Trace(err)
 	}
+	defer func() { b.auditResponse(ctx, AuditResponded, dtoReq.CustomerReferenceNumber, dtoResp, err) }()
+	defer func() { b.completeCRN(ctx, dtoReq.CustomerReferenceNumber, dtoParamResp, dtoResp, err) }()
 
-	logReq := dto.BuildLogRequest(InHouseTransferRequest, dtoReq)
-	b.log(ctx).Infof("%+v", logReq)
+	logReq := dto.BuildLogRequest(InHouseTransferRequest, dtoReq, b.redactor)
+	b.log(ctx).Infow(logReq.Operation, logMsgKey, logReq)
 
-	dtoResp, err := b.api.postDoPayment(ctx, dtoReq)
+	dtoResp, err = b.api.postDoPayment(ctx, dtoReq)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logResp := dto.BuildLogResponse(InHouseTransferResponse, dtoResp)
-	b.log(ctx).Infof("%+v", logResp)
+	logResp := dto.BuildLogResponse(InHouseTransferResponse, dtoResp, b.redactor)
+	b.log(ctx).Infow(logResp.Operation, logMsgKey, logResp)
 
-	dtoParamResp := dtoResp.DoPaymentResponse
+	dtoParamResp = dtoResp.DoPaymentResponse
 	if dtoParamResp == nil {
-		b.log(ctx).Error(BadResponseError)
-		return nil, BadResponseError
+		responseErr := newResponseError(dtoResp)
+		b.log(ctx).Error(responseErr)
+		return nil, responseErr
 	}
 
 	b.log(ctx).Info("=== END DO_PAYMENT ===")
@@ -172,33 +253,37 @@ func (b *BNI) DoPayment(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto
 	return dtoParamResp, nil
 }
 
-func (b *BNI) GetPaymentStatus(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) GetPaymentStatus(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (dtoParamResp *dto.GetPaymentStatusResponse, err error) {
+	ctx, end := b.begin(ctx, GetPaymentStatusOperation, dtoReq)
+	var dtoResp *dto.ApiResponse
+	defer func() { end(dtoResp, err) }()
 
 	b.log(ctx).Info("=== GET_PAYMENT_STATUS ===")
 
 	dtoReq.ClientID = b.config.ClientID
-	if err := b.setSignatureGetPaymentStatus(dtoReq); err != nil {
+	if err := b.setSignature(ctx, dtoReq); err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logReq := dto.BuildLogRequest(PaymentStatusRequest, dtoReq)
-	b.log(ctx).Infof("%+v", logReq)
+	logReq := dto.BuildLogRequest(PaymentStatusRequest, dtoReq, b.redactor)
+	b.log(ctx).Infow(logReq.Operation, logMsgKey, logReq)
 
-	dtoResp, err := b.api.postGetPaymentStatus(ctx, dtoReq)
+	dtoResp, err = b.api.postGetPaymentStatus(ctx, dtoReq)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
+	b.auditResponse(ctx, AuditResolved, dtoReq.CustomerReferenceNumber, dtoResp, nil)
 
-	logResp := dto.BuildLogResponse(PaymentStatusResponse, dtoResp)
-	b.log(ctx).Infof("%+v", logResp)
+	logResp := dto.BuildLogResponse(PaymentStatusResponse, dtoResp, b.redactor)
+	b.log(ctx).Infow(logResp.Operation, logMsgKey, logResp)
 
-	dtoParamResp := dtoResp.GetPaymentStatusResponse
+	dtoParamResp = dtoResp.GetPaymentStatusResponse
 	if dtoParamResp == nil {
-		b.log(ctx).Error(BadResponseError)
-		return nil, BadResponseError
+		responseErr := newResponseError(dtoResp)
+		b.log(ctx).Error(responseErr)
+		return nil, responseErr
 	}
 
 	b.log(ctx).Info("=== END GET_PAYMENT_STATUS ===")
@@ -206,33 +291,46 @@ func (b *BNI) GetPaymentStatus(ctx context.Context, dtoReq *dto.GetPaymentStatus
 	return dtoParamResp, nil
 }
 
-func (b *BNI) GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (dtoParamResp *dto.GetInterBankInquiryResponse, err error) {
+	ctx, end := b.begin(ctx, GetInterBankInquiryOperation, dtoReq)
+	var dtoResp *dto.ApiResponse
+	defer func() { end(dtoResp, err) }()
 
 	b.log(ctx).Info("=== GET_INTER_BANK_INQUIRY ===")
 
+	if err := b.fillCRN(ctx, &dtoReq.CustomerReferenceNumber); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	if err := b.validate(dtoReq); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
 	dtoReq.ClientID = b.config.ClientID
-	if err := b.setSignatureGetInterBankInquiry(dtoReq); err != nil {
+	if err := b.setSignature(ctx, dtoReq); err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logReq := dto.BuildLogRequest(InterBankInquiryRequest, dtoReq)
-	b.log(ctx).Infof("%+v", logReq)
+	logReq := dto.BuildLogRequest(InterBankInquiryRequest, dtoReq, b.redactor)
+	b.log(ctx).Infow(logReq.Operation, logMsgKey, logReq)
 
-	dtoResp, err := b.api.postGetInterBankInquiry(ctx, dtoReq)
+	dtoResp, err = b.api.postGetInterBankInquiry(ctx, dtoReq)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logResp := dto.BuildLogResponse(InterBankInquiryResponse, dtoResp)
-	b.log(ctx).Infof("%+v", logResp)
+	logResp := dto.BuildLogResponse(InterBankInquiryResponse, dtoResp, b.redactor)
+	b.log(ctx).Infow(logResp.Operation, logMsgKey, logResp)
 
-	dtoParamResp := dtoResp.GetInterBankInquiryResponse
+	dtoParamResp = dtoResp.GetInterBankInquiryResponse
 	if dtoParamResp == nil {
-		b.log(ctx).Error(BadResponseError)
-		return nil, BadResponseError
+		responseErr := newResponseError(dtoResp)
+		b.log(ctx).Error(responseErr)
+		return nil, responseErr
 	}
 
 	b.log(ctx).Info("=== END GET_INTER_BANK_INQUIRY ===")
@@ -240,33 +338,79 @@ func (b *BNI) GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankI
 	return dtoParamResp, nil
 }
 
-func (b *BNI) GetInterBankPayment(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (*dto.GetInterBankPaymentResponse, error) {
-	ctx = bniCtx.WithBNISessID(ctx, b.api.bniSessID)
+func (b *BNI) GetInterBankPayment(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (dtoParamResp *dto.GetInterBankPaymentResponse, err error) {
+	ctx, end := b.begin(ctx, GetInterBankPaymentOperation, dtoReq)
+	var dtoResp *dto.ApiResponse
+	defer func() { end(dtoResp, err) }()
 
 	b.log(ctx).Info("=== GET_INTER_BANK_PAYMENT ===")
 
+	if err := b.checkPaymentsAllowed(); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	if err := b.fillCRN(ctx, &dtoReq.CustomerReferenceNumber); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
+	if err := b.validate(dtoReq); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
+
 	dtoReq.ClientID = b.config.ClientID
-	if err := b.setSignatureGetInterBankPayment(dtoReq); err != nil {
+	if err := b.setSignature(ctx, dtoReq); err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
 
-	logReq := dto.BuildLogRequest(InterBankTransferRequest, dtoReq)
-	b.log(ctx).Infof("%+v", logReq)
+	hash, err := payloadHash(dtoReq)
+	if err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
 
-	dtoResp, err := b.api.postGetInterBankPayment(ctx, dtoReq)
+	stored, err := b.reserveCRN(ctx, dtoReq.CustomerReferenceNumber, hash)
 	if err != nil {
 		b.log(ctx).Error(errors.Details(err))
 		return nil, errors.Trace(err)
 	}
+	if stored != nil {
+		b.log(ctx).Infof("CRN %s already %s, returning the stored result", dtoReq.CustomerReferenceNumber, stored.State)
+		dtoParamResp = &dto.GetInterBankPaymentResponse{}
+		if err := stored.replay(dtoParamResp); err != nil {
+			return nil, err
+		}
+		return dtoParamResp, nil
+	}
 
-	logResp := dto.BuildLogResponse(InterBankTransferResponse, dtoResp)
-	b.log(ctx).Infof("%+v", logResp)
+	if err := b.auditInitiated(ctx, dtoReq.CustomerReferenceNumber, hash); err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		b.releaseCRN(ctx, dtoReq.CustomerReferenceNumber)
+		return nil, errors.Trace(err)
+	}
+	defer func() { b.auditResponse(ctx, AuditResponded, dtoReq.CustomerReferenceNumber, dtoResp, err) }()
+	defer func() { b.completeCRN(ctx, dtoReq.CustomerReferenceNumber, dtoParamResp, dtoResp, err) }()
+
+	logReq := dto.BuildLogRequest(InterBankTransferRequest, dtoReq, b.redactor)
+	b.log(ctx).Infow(logReq.Operation, logMsgKey, logReq)
+
+	dtoResp, err = b.api.postGetInterBankPayment(ctx, dtoReq)
+	if err != nil {
+		b.log(ctx).Error(errors.Details(err))
+		return nil, errors.Trace(err)
+	}
 
-	dtoParamResp := dtoResp.GetInterBankPaymentResponse
+	logResp := dto.BuildLogResponse(InterBankTransferResponse, dtoResp, b.redactor)
+	b.log(ctx).Infow(logResp.Operation, logMsgKey, logResp)
+
+	dtoParamResp = dtoResp.GetInterBankPaymentResponse
 	if dtoParamResp == nil {
-		b.log(ctx).Error(BadResponseError)
-		return nil, BadResponseError
+		responseErr := newResponseError(dtoResp)
+		b.log(ctx).Error(responseErr)
+		return nil, responseErr
 	}
 
 	b.log(ctx).Info("=== END GET_INTER_BANK_PAYMENT ===")
@@ -276,95 +420,107 @@ func (b *BNI) GetInterBankPayment(ctx context.Context, dtoReq *dto.GetInterBankP
 
 // === misc func ===
 
-func (b *BNI) log(ctx context.Context) *zap.SugaredLogger {
-	return logger.Logger(bniCtx.WithBNISessID(ctx, b.api.bniSessID))
-}
+// begin prepares ctx for a public operation, the returned func must be called
+// once the operation is finished.
+func (b *BNI) begin(ctx context.Context, operation string, dtoReq interface{}) (context.Context, func(*dto.ApiResponse, error)) {
+	ctx = bniCtx.WithBNISessID(ctx, b.api.getBNISessID())
+	ctx = bniCtx.WithOperation(ctx, operation)
+	start := time.Now()
 
-// === Signature of each request ===
+	attrs := append(requestAttributes(dtoReq), operationAttr.String(operation))
+	ctx, span := startSpan(ctx, b.tracer, "BNI "+operation, attrs...)
 
-func (b *BNI) setSignatureGetBalance(dtoReq *dto.GetBalanceRequest) error {
-	sign, err := b.signature.Sha256WithRSA(dtoReq.ClientID + dtoReq.AccountNo)
-	if err != nil {
-		return errors.Trace(err)
+	return ctx, func(dtoResp *dto.ApiResponse, err error) {
+		rc := dtoResp.CommonParam().ResponseCode
+		if rc != "" {
+			span.SetAttributes(responseCodeAttr.String(rc))
+		}
+		endSpan(span, err)
+
+		b.metrics.ObserveOperation(operation, metrics.RCCategory(rc), err, time.Since(start))
 	}
-	dtoReq.Signature = sign
+}
 
-	return nil
+func (b *BNI) log(ctx context.Context) *zap.SugaredLogger {
+	return logger.WithContext(b.logger, bniCtx.WithBNISessID(ctx, b.api.getBNISessID()))
 }
 
-func (b *BNI) setSignatureGetInHouseInquiry(dtoReq *dto.GetInHouseInquiryRequest) error {
-	sign, err := b.signature.Sha256WithRSA(dtoReq.ClientID + dtoReq.AccountNo)
-	if err != nil {
-		return errors.Trace(err)
+// validate runs the request validator, if any, on the requests it knows.
+func (b *BNI) validate(dtoReq interface{}) error {
+	if b.validator == nil {
+		return nil
 	}
-	dtoReq.Signature = sign
 
+	switch req := dtoReq.(type) {
+	case *dto.DoPaymentRequest:
+		return b.validator.ValidateDoPayment(req)
+	case *dto.GetInterBankInquiryRequest:
+		return b.validator.ValidateInterBankInquiry(req)
+	case *dto.GetInterBankPaymentRequest:
+		return b.validator.ValidateInterBankPayment(req)
+	}
 	return nil
 }
 
-func (b *BNI) setSignatureDoPayment(dtoReq *dto.DoPaymentRequest) error {
-	sign, err := b.signature.Sha256WithRSA(
-		dtoReq.ClientID +
-			dtoReq.CustomerReferenceNumber +
-			dtoReq.PaymentMethod +
-			dtoReq.DebitAccountNo +
-			dtoReq.CreditAccountNo +
-			dtoReq.ValueAmount +
-			dtoReq.ValueCurrency,
-	)
+// fillCRN sets an empty customer reference number from the CRN generator, if any.
+func (b *BNI) fillCRN(ctx context.Context, crn *string) error {
+	if *crn != "" || b.crnGen == nil {
+		return nil
+	}
 
+	generated, err := b.crnGen.Next(ctx)
 	if err != nil {
-		return errors.Trace(err)
+		return errors.Annotate(err, "generate CRN")
 	}
-	dtoReq.Signature = sign
+	*crn = generated
+	trace.SpanFromContext(ctx).SetAttributes(crnAttr.String(generated))
 
 	return nil
 }
 
-func (b *BNI) setSignatureGetPaymentStatus(dtoReq *dto.GetPaymentStatusRequest) error {
-	sign, err := b.signature.Sha256WithRSA(
-		dtoReq.ClientID +
-			dtoReq.CustomerReferenceNumber,
-	)
-
-	if err != nil {
-		return errors.Trace(err)
+// checkPaymentsAllowed guards operations that move money.
+func (b *BNI) checkPaymentsAllowed() error {
+	if b.config.IsProduction() && !b.config.AllowProductionPayments {
+		return ErrProductionPaymentsDisabled
 	}
-	dtoReq.Signature = sign
-
 	return nil
 }
 
-func (b *BNI) setSignatureGetInterBankInquiry(dtoReq *dto.GetInterBankInquiryRequest) error {
-	sign, err := b.signature.Sha256WithRSA(
-		dtoReq.ClientID +
-			dtoReq.DestinationBankCode +
-			dtoReq.DestinationAccountNum +
-			dtoReq.AccountNum,
-	)
+// newConfigLogger logs to config.LogPath when set and discards everything
+// otherwise. An invalid config.LogLevel is returned along with a logger at
+// info, config.Validate reports it too.
+func newConfigLogger(config config.Config) (*zap.Logger, error) {
+	if config.LogPath == "" {
+		return zap.NewNop(), nil
+	}
 
-	if err != nil {
-		return errors.Trace(err)
+	level := zap.InfoLevel
+	var err error
+	if config.LogLevel != "" {
+		if unmarshalErr := level.UnmarshalText([]byte(config.LogLevel)); unmarshalErr != nil {
+			level = zap.InfoLevel
+			err = errors.NotValidf("logLevel %q", config.LogLevel)
+		}
 	}
-	dtoReq.Signature = sign
 
-	return nil
+	return logger.NewFileLogger(config.LogPath, level), err
 }
 
-func (b *BNI) setSignatureGetInterBankPayment(dtoReq *dto.GetInterBankPaymentRequest) error {
-	sign, err := b.signature.Sha256WithRSA(
-		dtoReq.ClientID +
-			dtoReq.DestinationAccountNum +
-			dtoReq.DestinationBankCode +
-			dtoReq.AccountNum +
-			dtoReq.Amount +
-			dtoReq.RetrievalReffNum,
-	)
+// === Signature of each request ===
+
+func (b *BNI) sign(ctx context.Context, data string) (sign string, err error) {
+	_, span := startSpan(ctx, b.tracer, "BNI signature")
+	defer func() { endSpan(span, err) }()
+
+	return b.signature.Sha256WithRSA(data)
+}
 
+func (b *BNI) setSignature(ctx context.Context, dtoReq dto.Signable) error {
+	sign, err := b.sign(ctx, dtoReq.SignatureData())
 	if err != nil {
 		return errors.Trace(err)
 	}
-	dtoReq.Signature = sign
+	dtoReq.SetSignature(sign)
 
 	return nil
 }
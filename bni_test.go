@@ -3,6 +3,8 @@ package bni
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -13,10 +15,14 @@ import (
 
 	"github.com/fundex-id/bni-api-mgmt/config"
 	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
+	"github.com/fundex-id/bni-api-mgmt/crn"
 	"github.com/fundex-id/bni-api-mgmt/dto"
 	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
 	"github.com/lithammer/shortuuid"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 var testLogPath string = "test.log"
@@ -96,6 +102,12 @@ func TestBNI_DoAuthentication(t *testing.T) {
 		if util.AssertErrNotNil(t, err) {
 			assert.Empty(t, bni.api.accessToken)
 			assert.Empty(t, bni.api.bniSessID)
+
+			httpErr, ok := errors.Cause(err).(*HTTPError)
+			if assert.True(t, ok) {
+				assert.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+				assert.Empty(t, httpErr.BodySnippet)
+			}
 		}
 	})
 }
@@ -177,6 +189,56 @@ func TestBNI_GetBalance(t *testing.T) {
 
 		util.AssertErrNotNil(t, err)
 		assert.Empty(t, dtoResp)
+		assert.Equal(t, BadResponseError, errors.Cause(err))
+
+		responseErr, ok := AsResponseError(errors.Annotate(err, "balance"))
+		if assert.True(t, ok) {
+			assert.Equal(t, "0001", responseErr.ResponseCode)
+			assert.Equal(t, "Request has been processed successfully", responseErr.ResponseMessage)
+		}
+		assert.True(t, HasResponseCode(err, "0100", "0001"))
+		assert.False(t, HasResponseCode(err, "0100"))
+	})
+
+	t.Run("html error page", func(t *testing.T) {
+		givenConfig := config.Config{
+			Username:        "dummyusername",
+			Password:        "dummypassword",
+			LogPath:         testLogPath,
+			SignatureConfig: dummySignatureConfig,
+		}
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, BalancePath, req.URL.Path)
+
+			w.Header().Set("content-type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusBadGateway)
+			_, err := w.Write([]byte("<html><body>Request Rejected</body></html>"))
+			util.AssertErrNil(t, err)
+		}))
+		defer testServer.Close()
+
+		givenConfig.BNIServer = testServer.URL
+
+		bni := New(givenConfig)
+		bni.api.httpClient = testServer.Client()
+
+		dtoReq := dto.GetBalanceRequest{
+			AccountNo: "115471119",
+		}
+
+		ctx := bniCtx.WithHTTPReqID(context.Background(), shortuuid.New())
+		dtoResp, err := bni.GetBalance(ctx, &dtoReq)
+
+		assert.Nil(t, dtoResp)
+		if util.AssertErrNotNil(t, err) {
+			httpErr, ok := errors.Cause(err).(*HTTPError)
+			if assert.True(t, ok) {
+				assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+				assert.Equal(t, "text/html; charset=utf-8", httpErr.Header.Get("content-type"))
+				assert.Contains(t, httpErr.BodySnippet, "Request Rejected")
+			}
+		}
 	})
 
 	t.Run("no auth then good response", func(t *testing.T) {
@@ -323,6 +385,92 @@ func TestBNI_DoPayment(t *testing.T) {
 	})
 }
 
+func TestBNI_WithCRNGenerator(t *testing.T) {
+	generator, err := crn.New(crn.NewMemoryStore())
+	util.AssertErrNil(t, err)
+
+	bni, testServer := buildBNIAndMockServerGoodResponse(t,
+		config.Config{SignatureConfig: dummySignatureConfig},
+		InHouseTransferPath,
+		"testdata/get_dopayment_response.json",
+		WithCRNGenerator(generator),
+	)
+	defer testServer.Close()
+
+	dtoReq := dto.DoPaymentRequest{DebitAccountNo: "113183203", CreditAccountNo: "115471119", ValueAmount: "100500"}
+	_, err = bni.DoPayment(context.Background(), &dtoReq)
+	util.AssertErrNil(t, err)
+	assert.Len(t, dtoReq.CustomerReferenceNumber, crn.MaxLength)
+
+	given := dto.DoPaymentRequest{CustomerReferenceNumber: "20170227000000000020"}
+	_, err = bni.DoPayment(context.Background(), &given)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, "20170227000000000020", given.CustomerReferenceNumber)
+}
+
+func TestBNI_ProductionPaymentsGuard(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request to %s", req.URL.Path)
+	}))
+	defer testServer.Close()
+
+	bni := New(config.Config{
+		Environment:     config.Production,
+		BNIServer:       testServer.URL,
+		SignatureConfig: dummySignatureConfig,
+	})
+	bni.api.httpClient = testServer.Client()
+
+	ctx := context.Background()
+
+	_, err := bni.DoPayment(ctx, &dto.DoPaymentRequest{CustomerReferenceNumber: "20170227000000000020"})
+	assert.Equal(t, ErrProductionPaymentsDisabled, errors.Cause(err))
+
+	_, err = bni.GetInterBankPayment(ctx, &dto.GetInterBankPaymentRequest{CustomerReferenceNumber: "20170227000000000021"})
+	assert.Equal(t, ErrProductionPaymentsDisabled, errors.Cause(err))
+}
+
+type rejectingValidator struct{}
+
+func (rejectingValidator) ValidateDoPayment(dtoReq *dto.DoPaymentRequest) error {
+	return nil
+}
+
+func (rejectingValidator) ValidateInterBankInquiry(dtoReq *dto.GetInterBankInquiryRequest) error {
+	return errors.NotFoundf("destination bank code %s", dtoReq.DestinationBankCode)
+}
+
+func (rejectingValidator) ValidateInterBankPayment(dtoReq *dto.GetInterBankPaymentRequest) error {
+	dtoReq.DestinationBankName = "Bank Central Asia"
+	return nil
+}
+
+func TestBNI_WithRequestValidator(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request to %s", req.URL.Path)
+	}))
+	defer testServer.Close()
+
+	bni := New(config.Config{BNIServer: testServer.URL, SignatureConfig: dummySignatureConfig}, WithRequestValidator(rejectingValidator{}))
+	bni.api.httpClient = testServer.Client()
+
+	_, err := bni.GetInterBankInquiry(context.Background(), &dto.GetInterBankInquiryRequest{DestinationBankCode: "999"})
+	assert.True(t, errors.IsNotFound(err), "rejected before it is sent")
+
+	payment, server := buildBNIAndMockServerGoodResponse(t,
+		config.Config{SignatureConfig: dummySignatureConfig},
+		InterBankTransferPath,
+		"testdata/get_getinterbankpayment_response.json",
+		WithRequestValidator(rejectingValidator{}),
+	)
+	defer server.Close()
+
+	dtoReq := dto.GetInterBankPaymentRequest{CustomerReferenceNumber: "20170227000000000021", DestinationBankCode: "014"}
+	_, err = payment.GetInterBankPayment(context.Background(), &dtoReq)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, "Bank Central Asia", dtoReq.DestinationBankName)
+}
+
 func TestBNI_GetPaymentStatus(t *testing.T) {
 	t.Run("good case", func(t *testing.T) {
 		givenConfig := config.Config{
@@ -411,6 +559,77 @@ func TestBNI_GetInterBankPayment(t *testing.T) {
 	})
 }
 
+func TestBNI_WithLogger(t *testing.T) {
+	observedCore, observedLogs := observer.New(zap.InfoLevel)
+
+	bni, testServer := buildBNIAndMockServerGoodResponse(t, config.Config{
+		SignatureConfig: dummySignatureConfig,
+	}, BalancePath, "testdata/get_balance_response.json", WithLogger(zap.New(observedCore)))
+	defer testServer.Close()
+
+	dtoReq := dto.GetBalanceRequest{AccountNo: "115471119"}
+	ctx := bniCtx.WithHTTPReqID(context.Background(), "req-1")
+	_, err := bni.GetBalance(ctx, &dtoReq)
+	util.AssertErrNil(t, err)
+
+	entries := observedLogs.FilterField(zap.String(bniCtx.HTTPReqIDKey, "req-1")).All()
+	if assert.NotEmpty(t, entries) {
+		assert.Equal(t, "=== GET_BALANCE ===", entries[0].Message)
+	}
+
+	for _, entry := range observedLogs.All() {
+		logged := fmt.Sprint(entry.Message, entry.ContextMap())
+		assert.NotContains(t, logged, dtoReq.Signature)
+		assert.NotContains(t, logged, dtoReq.AccountNo)
+		assert.NotContains(t, logged, "JONOMADE")
+	}
+
+	other := New(config.Config{})
+	assert.NotSame(t, bni.logger, other.logger)
+}
+
+func TestNewConfigLogger(t *testing.T) {
+	_, err := newConfigLogger(config.Config{LogPath: testLogPath, LogLevel: "debug"})
+	util.AssertErrNil(t, err)
+
+	configLogger, err := newConfigLogger(config.Config{LogPath: testLogPath, LogLevel: "verbose"})
+	assert.True(t, errors.IsNotValid(err))
+	assert.True(t, configLogger.Core().Enabled(zap.InfoLevel))
+	assert.False(t, configLogger.Core().Enabled(zap.DebugLevel))
+}
+
+func TestBNI_LogMsgFields(t *testing.T) {
+	observedCore, observedLogs := observer.New(zap.InfoLevel)
+
+	bni, testServer := buildBNIAndMockServerGoodResponse(t, config.Config{
+		SignatureConfig: dummySignatureConfig,
+	}, InHouseTransferPath, "testdata/get_dopayment_response.json", WithLogger(zap.New(observedCore)))
+	defer testServer.Close()
+
+	_, err := bni.DoPayment(context.Background(), &dto.DoPaymentRequest{
+		CustomerReferenceNumber: "20170227000000000020",
+		DebitAccountNo:          "113183203",
+		CreditAccountNo:         "115471119",
+	})
+	util.AssertErrNil(t, err)
+
+	logReq, ok := observedLogs.FilterMessage(InHouseTransferRequest).All()[0].ContextMap()[logMsgKey].(dto.LogMsg)
+	if assert.True(t, ok) {
+		assert.Equal(t, dto.DirectionRequest, logReq.Direction)
+		assert.Equal(t, "20170227000000000020", logReq.CRN)
+		assert.Empty(t, logReq.RC)
+	}
+
+	logResp, ok := observedLogs.FilterMessage(InHouseTransferResponse).All()[0].ContextMap()[logMsgKey].(dto.LogMsg)
+	if assert.True(t, ok) {
+		assert.Equal(t, dto.DirectionResponse, logResp.Direction)
+		assert.Equal(t, "0001", logResp.RC)
+		assert.Equal(t, "20170227000000000020", logResp.CRN)
+		assert.Equal(t, "953403", logResp.BankReference)
+		assert.Equal(t, http.StatusOK, logResp.HTTPStatus)
+	}
+}
+
 func basicAuth(username, password string) string {
 	auth := username + ":" + password
 	return base64.StdEncoding.EncodeToString([]byte(auth))
@@ -440,7 +659,11 @@ func getJSON(filePath string) []byte {
 	return byteValue
 }
 
-func buildBNIAndMockServerGoodResponse(t *testing.T, givenConfig config.Config, assertPath string, jsonPathTestData string) (bni *BNI, testServer *httptest.Server) {
+func decodeJSONBody(req *http.Request, v interface{}) error {
+	return json.NewDecoder(req.Body).Decode(v)
+}
+
+func buildBNIAndMockServerGoodResponse(t *testing.T, givenConfig config.Config, assertPath string, jsonPathTestData string, opts ...Option) (bni *BNI, testServer *httptest.Server) {
 	t.Helper()
 
 	testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -459,7 +682,7 @@ func buildBNIAndMockServerGoodResponse(t *testing.T, givenConfig config.Config,
 
 	givenConfig.BNIServer = testServer.URL
 
-	bni = New(givenConfig)
+	bni = New(givenConfig, opts...)
 	bni.api.httpClient = testServer.Client()
 
 	return bni, testServer
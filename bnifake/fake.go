@@ -0,0 +1,214 @@
+// Package bnifake provides an in-memory bni.Client for unit tests.
+//
+//	fake := bnifake.New()
+//	fake.QueueDoPayment(&dto.DoPaymentResponse{...}, nil)
+//	err := payout(ctx, fake) // code under test takes a bni.Client
+//	calls := fake.CallsTo(bni.DoPaymentOperation)
+package bnifake
+
+import (
+	"context"
+	"sync"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+// ErrNotScripted is returned by an operation with no queued result and no Func set.
+var ErrNotScripted = errors.New("bnifake: no scripted response")
+
+// Call is one recorded invocation, Request is the pointer passed by the caller.
+type Call struct {
+	Operation string
+	Request   interface{}
+}
+
+type result struct {
+	resp interface{}
+	err  error
+}
+
+// Fake implements bni.Client. Each operation first calls its Func field when
+// set, otherwise returns the next result queued for it. The Func fields must
+// be set before the Fake is used concurrently.
+type Fake struct {
+	DoAuthenticationFunc    func(ctx context.Context) (*dto.GetTokenResponse, error)
+	GetBalanceFunc          func(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*dto.GetBalanceResponse, error)
+	GetInHouseInquiryFunc   func(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error)
+	DoPaymentFunc           func(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error)
+	GetPaymentStatusFunc    func(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error)
+	GetInterBankInquiryFunc func(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error)
+	GetInterBankPaymentFunc func(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (*dto.GetInterBankPaymentResponse, error)
+
+	mutex   sync.Mutex
+	calls   []Call
+	results map[string][]result
+}
+
+var _ bni.Client = (*Fake)(nil)
+
+func New() *Fake {
+	return &Fake{results: map[string][]result{}}
+}
+
+// === scripting ===
+
+func (f *Fake) QueueDoAuthentication(resp *dto.GetTokenResponse, err error) *Fake {
+	return f.queue(bni.DoAuthOperation, resp, err)
+}
+
+func (f *Fake) QueueGetBalance(resp *dto.GetBalanceResponse, err error) *Fake {
+	return f.queue(bni.GetBalanceOperation, resp, err)
+}
+
+func (f *Fake) QueueGetInHouseInquiry(resp *dto.GetInHouseInquiryResponse, err error) *Fake {
+	return f.queue(bni.GetInHouseInquiryOperation, resp, err)
+}
+
+func (f *Fake) QueueDoPayment(resp *dto.DoPaymentResponse, err error) *Fake {
+	return f.queue(bni.DoPaymentOperation, resp, err)
+}
+
+func (f *Fake) QueueGetPaymentStatus(resp *dto.GetPaymentStatusResponse, err error) *Fake {
+	return f.queue(bni.GetPaymentStatusOperation, resp, err)
+}
+
+func (f *Fake) QueueGetInterBankInquiry(resp *dto.GetInterBankInquiryResponse, err error) *Fake {
+	return f.queue(bni.GetInterBankInquiryOperation, resp, err)
+}
+
+func (f *Fake) QueueGetInterBankPayment(resp *dto.GetInterBankPaymentResponse, err error) *Fake {
+	return f.queue(bni.GetInterBankPaymentOperation, resp, err)
+}
+
+// === recording ===
+
+// Calls returns every recorded call in order.
+func (f *Fake) Calls() []Call {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]Call{}, f.calls...)
+}
+
+// CallsTo returns the recorded calls of one operation, e.g. bni.DoPaymentOperation.
+func (f *Fake) CallsTo(operation string) []Call {
+	var calls []Call
+	for _, call := range f.Calls() {
+		if call.Operation == operation {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets recorded calls and queued results, Func fields are kept.
+func (f *Fake) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.calls = nil
+	f.results = map[string][]result{}
+}
+
+// === bni.Client ===
+
+func (f *Fake) DoAuthentication(ctx context.Context) (*dto.GetTokenResponse, error) {
+	f.record(bni.DoAuthOperation, nil)
+	if f.DoAuthenticationFunc != nil {
+		return f.DoAuthenticationFunc(ctx)
+	}
+	resp, err := f.next(bni.DoAuthOperation)
+	dtoResp, _ := resp.(*dto.GetTokenResponse)
+	return dtoResp, err
+}
+
+func (f *Fake) GetBalance(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*dto.GetBalanceResponse, error) {
+	f.record(bni.GetBalanceOperation, dtoReq)
+	if f.GetBalanceFunc != nil {
+		return f.GetBalanceFunc(ctx, dtoReq)
+	}
+	resp, err := f.next(bni.GetBalanceOperation)
+	dtoResp, _ := resp.(*dto.GetBalanceResponse)
+	return dtoResp, err
+}
+
+func (f *Fake) GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error) {
+	f.record(bni.GetInHouseInquiryOperation, dtoReq)
+	if f.GetInHouseInquiryFunc != nil {
+		return f.GetInHouseInquiryFunc(ctx, dtoReq)
+	}
+	resp, err := f.next(bni.GetInHouseInquiryOperation)
+	dtoResp, _ := resp.(*dto.GetInHouseInquiryResponse)
+	return dtoResp, err
+}
+
+func (f *Fake) DoPayment(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error) {
+	f.record(bni.DoPaymentOperation, dtoReq)
+	if f.DoPaymentFunc != nil {
+		return f.DoPaymentFunc(ctx, dtoReq)
+	}
+	resp, err := f.next(bni.DoPaymentOperation)
+	dtoResp, _ := resp.(*dto.DoPaymentResponse)
+	return dtoResp, err
+}
+
+func (f *Fake) GetPaymentStatus(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error) {
+	f.record(bni.GetPaymentStatusOperation, dtoReq)
+	if f.GetPaymentStatusFunc != nil {
+		return f.GetPaymentStatusFunc(ctx, dtoReq)
+	}
+	resp, err := f.next(bni.GetPaymentStatusOperation)
+	dtoResp, _ := resp.(*dto.GetPaymentStatusResponse)
+	return dtoResp, err
+}
+
+func (f *Fake) GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error) {
+	f.record(bni.GetInterBankInquiryOperation, dtoReq)
+	if f.GetInterBankInquiryFunc != nil {
+		return f.GetInterBankInquiryFunc(ctx, dtoReq)
+	}
+	resp, err := f.next(bni.GetInterBankInquiryOperation)
+	dtoResp, _ := resp.(*dto.GetInterBankInquiryResponse)
+	return dtoResp, err
+}
+
+func (f *Fake) GetInterBankPayment(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (*dto.GetInterBankPaymentResponse, error) {
+	f.record(bni.GetInterBankPaymentOperation, dtoReq)
+	if f.GetInterBankPaymentFunc != nil {
+		return f.GetInterBankPaymentFunc(ctx, dtoReq)
+	}
+	resp, err := f.next(bni.GetInterBankPaymentOperation)
+	dtoResp, _ := resp.(*dto.GetInterBankPaymentResponse)
+	return dtoResp, err
+}
+
+// === misc func ===
+
+func (f *Fake) queue(operation string, resp interface{}, err error) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.results[operation] = append(f.results[operation], result{resp: resp, err: err})
+	return f
+}
+
+func (f *Fake) record(operation string, dtoReq interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.calls = append(f.calls, Call{Operation: operation, Request: dtoReq})
+}
+
+func (f *Fake) next(operation string) (interface{}, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	queued := f.results[operation]
+	if len(queued) == 0 {
+		return nil, errors.Annotate(ErrNotScripted, operation)
+	}
+	f.results[operation] = queued[1:]
+	return queued[0].resp, queued[0].err
+}
@@ -0,0 +1,51 @@
+package bnifake
+
+import (
+	"context"
+	"testing"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	paymentErr := errors.New("timeout")
+
+	fake := New().
+		QueueDoPayment(nil, paymentErr).
+		QueueDoPayment(&dto.DoPaymentResponse{}, nil)
+
+	dtoReq := &dto.DoPaymentRequest{CustomerReferenceNumber: "20170227000000000020"}
+
+	_, err := fake.DoPayment(ctx, dtoReq)
+	assert.Equal(t, paymentErr, err)
+
+	dtoResp, err := fake.DoPayment(ctx, dtoReq)
+	assert.NoError(t, err)
+	assert.NotNil(t, dtoResp)
+
+	_, err = fake.DoPayment(ctx, dtoReq)
+	assert.Equal(t, ErrNotScripted, errors.Cause(err))
+
+	fake.GetBalanceFunc = func(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*dto.GetBalanceResponse, error) {
+		resp := &dto.GetBalanceResponse{}
+		resp.Parameters.AccountBalance = 100500
+		return resp, nil
+	}
+	balance, err := fake.GetBalance(ctx, &dto.GetBalanceRequest{AccountNo: "115471119"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100500), balance.Parameters.AccountBalance)
+
+	assert.Len(t, fake.Calls(), 4)
+	assert.Equal(t, []Call{
+		{Operation: bni.DoPaymentOperation, Request: dtoReq},
+		{Operation: bni.DoPaymentOperation, Request: dtoReq},
+		{Operation: bni.DoPaymentOperation, Request: dtoReq},
+	}, fake.CallsTo(bni.DoPaymentOperation))
+
+	fake.Reset()
+	assert.Empty(t, fake.Calls())
+}
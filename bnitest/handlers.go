@@ -0,0 +1,405 @@
+package bnitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/signature"
+)
+
+// Response codes returned by the simulator. RCSuccess is BNI's; the failure
+// codes are the simulator's own and only meant to be distinguishable.
+const (
+	RCSuccess           = "0001"
+	RCInvalidRequest    = "0100"
+	RCInvalidClientID   = "0101"
+	RCInvalidSignature  = "0102"
+	RCAccountNotFound   = "0103"
+	RCInsufficientFunds = "0104"
+	RCDuplicateCRN      = "0105"
+	RCPaymentNotFound   = "0106"
+	RCInvalidReference  = "0107"
+)
+
+const successMessage = "Request has been processed successfully"
+
+var rcMessages = map[string]string{
+	RCInvalidRequest:    "Invalid request",
+	RCInvalidClientID:   "Invalid client id",
+	RCInvalidSignature:  "Invalid signature",
+	RCAccountNotFound:   "Account not found",
+	RCInsufficientFunds: "Insufficient balance",
+	RCDuplicateCRN:      "Duplicate customer reference number",
+	RCPaymentNotFound:   "Payment not found",
+	RCInvalidReference:  "Invalid retrieval reference number",
+}
+
+func (s *Server) apiHandlers() map[string]func(http.ResponseWriter, *http.Request) {
+	return map[string]func(http.ResponseWriter, *http.Request){
+		bni.BalancePath:           s.handleGetBalance,
+		bni.InHouseInquiryPath:    s.handleGetInHouseInquiry,
+		bni.InHouseTransferPath:   s.handleDoPayment,
+		bni.PaymentStatusPath:     s.handleGetPaymentStatus,
+		bni.InterBankInquiryPath:  s.handleGetInterBankInquiry,
+		bni.InterBankTransferPath: s.handleGetInterBankPayment,
+	}
+}
+
+func (s *Server) handleGetBalance(w http.ResponseWriter, req *http.Request) {
+	var dtoReq dto.GetBalanceRequest
+	if !s.decode(w, req, &dtoReq, &dtoReq.CommonRequest) {
+		return
+	}
+
+	account, ok := s.accounts[dtoReq.AccountNo]
+	if !ok {
+		s.writeError(w, RCAccountNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"getBalanceResponse": &dto.GetBalanceResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.GetBalanceResponseParam{
+			CommonResponseParam: s.success(),
+			CustomerName:        account.Name,
+			AccountCurrency:     account.Currency,
+			AccountBalance:      account.Balance,
+		},
+	}})
+}
+
+func (s *Server) handleGetInHouseInquiry(w http.ResponseWriter, req *http.Request) {
+	var dtoReq dto.GetInHouseInquiryRequest
+	if !s.decode(w, req, &dtoReq, &dtoReq.CommonRequest) {
+		return
+	}
+
+	account, ok := s.accounts[dtoReq.AccountNo]
+	if !ok {
+		s.writeError(w, RCAccountNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"getInHouseInquiryResponse": &dto.GetInHouseInquiryResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.GetInHouseInquiryResponseParam{
+			CommonResponseParam: s.success(),
+			CustomerName:        account.Name,
+			AccountCurrency:     account.Currency,
+			AccountNumber:       account.Number,
+			AccountStatus:       account.Status,
+			AccountType:         account.Type,
+		},
+	}})
+}
+
+func (s *Server) handleDoPayment(w http.ResponseWriter, req *http.Request) {
+	var dtoReq dto.DoPaymentRequest
+	if !s.decode(w, req, &dtoReq, &dtoReq.CommonRequest) {
+		return
+	}
+
+	crn := dtoReq.CustomerReferenceNumber
+	if crn == "" {
+		s.writeError(w, RCInvalidRequest)
+		return
+	}
+	if _, exists := s.payments[crn]; exists {
+		s.writeError(w, RCDuplicateCRN)
+		return
+	}
+
+	payment := &Payment{
+		CRN:             crn,
+		DebitAccountNo:  dtoReq.DebitAccountNo,
+		CreditAccountNo: dtoReq.CreditAccountNo,
+		BankCode:        dtoReq.DestinationBankCode,
+		Currency:        dtoReq.ValueCurrency,
+		Time:            s.now(),
+	}
+	amount, err := strconv.ParseInt(dtoReq.ValueAmount, 10, 64)
+	if err != nil || amount <= 0 {
+		s.writeError(w, RCInvalidRequest)
+		return
+	}
+	payment.Amount = amount
+
+	// PaymentMethod 0 is an in-house transfer, anything else (RTGS, clearing)
+	// leaves BNI so the credit side is not tracked.
+	var credit *Account
+	if dtoReq.PaymentMethod == "" || dtoReq.PaymentMethod == "0" {
+		credit = s.accounts[dtoReq.CreditAccountNo]
+		if credit == nil {
+			s.failPayment(w, payment, RCAccountNotFound)
+			return
+		}
+	}
+	if rc := s.debit(dtoReq.DebitAccountNo, amount); rc != "" {
+		s.failPayment(w, payment, rc)
+		return
+	}
+	if credit != nil {
+		credit.Balance += amount
+	}
+	s.completePayment(payment)
+
+	writeJSON(w, map[string]interface{}{"doPaymentResponse": &dto.DoPaymentResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.DoPaymentResponseParam{
+			CommonResponseParam: s.success(),
+			DebitAccountNo:      parseInt(payment.DebitAccountNo),
+			CreditAccountNo:     parseInt(payment.CreditAccountNo),
+			ValueAmount:         payment.Amount,
+			ValueCurrency:       payment.Currency,
+			BankReference:       payment.BankReference,
+			CustomerReference:   number(crn),
+		},
+	}})
+}
+
+func (s *Server) handleGetPaymentStatus(w http.ResponseWriter, req *http.Request) {
+	var dtoReq dto.GetPaymentStatusRequest
+	if !s.decode(w, req, &dtoReq, &dtoReq.CommonRequest) {
+		return
+	}
+
+	payment, ok := s.payments[dtoReq.CustomerReferenceNumber]
+	if !ok {
+		s.writeError(w, RCPaymentNotFound)
+		return
+	}
+
+	previous := dto.GetPaymentStatusResponseParamPreviousResponse{
+		TransactionStatus:         "Y",
+		PreviousResponseTimestamp: timestamp(payment.Time),
+		DebitAccountNo:            parseInt(payment.DebitAccountNo),
+		CreditAccountNo:           parseInt(payment.CreditAccountNo),
+		ValueAmount:               payment.Amount,
+		ValueCurrency:             payment.Currency,
+	}
+	if !payment.Succeeded() {
+		previous.TransactionStatus = "N"
+		previous.PreviousResponseCode = payment.ResponseCode
+		previous.PreviousResponseMessage = payment.ResponseMessage
+	}
+
+	writeJSON(w, map[string]interface{}{"getPaymentStatusResponse": &dto.GetPaymentStatusResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.GetPaymentStatusResponseParam{
+			CommonResponseParam: s.success(),
+			PreviousResponse:    previous,
+			BankReference:       payment.BankReference,
+			CustomerReference:   number(payment.CRN),
+		},
+	}})
+}
+
+func (s *Server) handleGetInterBankInquiry(w http.ResponseWriter, req *http.Request) {
+	var dtoReq dto.GetInterBankInquiryRequest
+	if !s.decode(w, req, &dtoReq, &dtoReq.CommonRequest) {
+		return
+	}
+
+	if _, ok := s.accounts[dtoReq.AccountNum]; !ok {
+		s.writeError(w, RCAccountNotFound)
+		return
+	}
+	dest, ok := s.external[externalKey(dtoReq.DestinationBankCode, dtoReq.DestinationAccountNum)]
+	if !ok {
+		s.writeError(w, RCAccountNotFound)
+		return
+	}
+
+	reffNum := strconv.FormatInt(s.nextSeq(), 10)
+	s.inquiries[reffNum] = inquiry{
+		accountNum: dtoReq.AccountNum,
+		bankCode:   dest.BankCode,
+		destAccNum: dest.Number,
+	}
+
+	writeJSON(w, map[string]interface{}{"getInterBankInquiryResponse": &dto.GetInterBankInquiryResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.GetInterBankInquiryResponseParam{
+			CommonResponseParam:    s.success(),
+			DestinationAccountNum:  dest.Number,
+			DestinationAccountName: dest.Name,
+			DestinationBankName:    dest.BankName,
+			RetrievalReffNum:       json.Number(reffNum),
+		},
+	}})
+}
+
+func (s *Server) handleGetInterBankPayment(w http.ResponseWriter, req *http.Request) {
+	var dtoReq dto.GetInterBankPaymentRequest
+	if !s.decode(w, req, &dtoReq, &dtoReq.CommonRequest) {
+		return
+	}
+
+	crn := dtoReq.CustomerReferenceNumber
+	if crn == "" {
+		s.writeError(w, RCInvalidRequest)
+		return
+	}
+	if _, exists := s.payments[crn]; exists {
+		s.writeError(w, RCDuplicateCRN)
+		return
+	}
+
+	// The retrieval reference is single use and must match the inquiry it came from.
+	inq, ok := s.inquiries[dtoReq.RetrievalReffNum]
+	if !ok || inq.accountNum != dtoReq.AccountNum ||
+		inq.bankCode != dtoReq.DestinationBankCode || inq.destAccNum != dtoReq.DestinationAccountNum {
+		s.writeError(w, RCInvalidReference)
+		return
+	}
+	delete(s.inquiries, dtoReq.RetrievalReffNum)
+
+	payment := &Payment{
+		CRN:             crn,
+		InterBank:       true,
+		DebitAccountNo:  dtoReq.AccountNum,
+		CreditAccountNo: dtoReq.DestinationAccountNum,
+		BankCode:        dtoReq.DestinationBankCode,
+		Currency:        "IDR",
+		Time:            s.now(),
+	}
+	amount, err := strconv.ParseInt(dtoReq.Amount, 10, 64)
+	if err != nil || amount <= 0 {
+		s.writeError(w, RCInvalidRequest)
+		return
+	}
+	payment.Amount = amount
+
+	if rc := s.debit(dtoReq.AccountNum, amount); rc != "" {
+		s.failPayment(w, payment, rc)
+		return
+	}
+	s.completePayment(payment)
+
+	dest := s.external[externalKey(inq.bankCode, inq.destAccNum)]
+	writeJSON(w, map[string]interface{}{"getInterBankPaymentResponse": &dto.GetInterBankPaymentResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.GetInterBankPaymentResponseParam{
+			CommonResponseParam:    s.success(),
+			DestinationAccountNum:  number(dest.Number),
+			DestinationAccountName: dest.Name,
+			DestinationBankName:    dest.BankName,
+			CustomerReffNum:        number(crn),
+			AccountName:            s.accounts[dtoReq.AccountNum].Name,
+		},
+	}})
+}
+
+// === helpers ===
+
+// decode reads the request body into dtoReq and checks its client id and signature.
+// It writes the failure response itself and returns false when the request is rejected.
+func (s *Server) decode(w http.ResponseWriter, req *http.Request, dtoReq dto.Signable, common *dto.CommonRequest) bool {
+	if err := json.NewDecoder(req.Body).Decode(dtoReq); err != nil {
+		s.writeError(w, RCInvalidRequest)
+		return false
+	}
+	if common.ClientID != s.clientID {
+		s.writeError(w, RCInvalidClientID)
+		return false
+	}
+	if s.publicKey != nil {
+		if err := signature.Verify(s.publicKey, dtoReq.SignatureData(), common.Signature); err != nil {
+			s.writeError(w, RCInvalidSignature)
+			return false
+		}
+	}
+	return true
+}
+
+// debit returns a failure response code, or "" once the amount is taken.
+func (s *Server) debit(accountNo string, amount int64) string {
+	account, ok := s.accounts[accountNo]
+	if !ok {
+		return RCAccountNotFound
+	}
+	if account.Balance < amount {
+		return RCInsufficientFunds
+	}
+	account.Balance -= amount
+	return ""
+}
+
+func (s *Server) completePayment(payment *Payment) {
+	payment.ResponseCode = RCSuccess
+	payment.ResponseMessage = successMessage
+	payment.BankReference = s.nextSeq()
+	s.payments[payment.CRN] = payment
+}
+
+// failPayment records a rejected payment so its status can still be looked up.
+func (s *Server) failPayment(w http.ResponseWriter, payment *Payment, rc string) {
+	payment.ResponseCode = rc
+	payment.ResponseMessage = rcMessages[rc]
+	s.payments[payment.CRN] = payment
+	s.writeError(w, rc)
+}
+
+func (s *Server) nextSeq() int64 {
+	s.seq++
+	return s.seq
+}
+
+func (s *Server) success() dto.CommonResponseParam {
+	return dto.CommonResponseParam{
+		ResponseCode:      RCSuccess,
+		ResponseMessage:   successMessage,
+		ResponseTimestamp: timestamp(s.now()),
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, rc string) {
+	writeJSON(w, map[string]interface{}{"Response": &dto.BadRespResponse{
+		CommonResponse: dto.CommonResponse{ClientID: s.clientID},
+		Parameters: dto.CommonResponseParam{
+			ResponseCode:      rc,
+			ResponseMessage:   "Request has been failed",
+			ErrorMessage:      rcMessages[rc],
+			ResponseTimestamp: timestamp(s.now()),
+		},
+	}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func externalKey(bankCode, accountNo string) string {
+	return bankCode + "/" + accountNo
+}
+
+// parseInt converts an account number for the numeric response fields, 0 if it isn't one.
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// number returns s as a json.Number, empty (and so omitted) if s isn't numeric.
+func number(s string) json.Number {
+	if _, err := strconv.ParseUint(s, 10, 64); err != nil {
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return ""
+		}
+	}
+	return json.Number(s)
+}
@@ -0,0 +1,401 @@
+// Package bnitest runs a stateful, in-process simulation of the BNI H2H gateway
+// for integration tests.
+//
+//	srv := bnitest.NewServer(
+//		bnitest.WithPublicKey(publicKey),
+//		bnitest.WithAccount(bnitest.Account{Number: "113183203", Name: "FUNDEX", Balance: 1000000}),
+//	)
+//	defer srv.Close()
+//
+//	cfg := srv.Config()
+//	cfg.SignatureConfig = signatureConfig
+//	client := bni.New(cfg)
+//
+// Unlike bnifake, requests go over real HTTP through the whole client stack:
+// token refresh, signing, retries and response decoding.
+package bnitest
+
+import (
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/lithammer/shortuuid"
+)
+
+const (
+	DefaultUsername = "bnitest"
+	DefaultPassword = "bnitest"
+	DefaultClientID = "BNITEST"
+	DefaultTokenTTL = time.Hour
+)
+
+// Account is an account held at BNI by the simulated customer.
+type Account struct {
+	Number   string
+	Name     string
+	Currency string // defaults to IDR
+	Balance  int64
+	Status   string // defaults to "Baik"
+	Type     string // defaults to "Giro"
+}
+
+// ExternalAccount is an account at another bank, reachable through interbank inquiry and payment.
+type ExternalAccount struct {
+	BankCode string
+	BankName string
+	Number   string
+	Name     string
+}
+
+// Payment is a transfer the simulator has processed, keyed by customer reference number.
+type Payment struct {
+	CRN             string
+	InterBank       bool
+	DebitAccountNo  string
+	CreditAccountNo string
+	BankCode        string
+	Amount          int64
+	Currency        string
+	BankReference   int64
+	ResponseCode    string
+	ResponseMessage string
+	Time            time.Time
+}
+
+// Succeeded reports whether the payment moved money.
+func (p Payment) Succeeded() bool {
+	return p.ResponseCode == RCSuccess
+}
+
+// Fault makes matching requests misbehave before they reach the simulator.
+// Latency alone only delays the request; with StatusCode set the request is
+// answered with StatusCode, ContentType and Body instead of being processed.
+type Fault struct {
+	Path        string // empty matches every path
+	Latency     time.Duration
+	StatusCode  int
+	ContentType string
+	Body        string
+	Count       int // number of requests affected, 0 means until ClearFaults
+}
+
+// MalformedJSON is a Body for Fault that a JSON decoder rejects.
+const MalformedJSON = `{"getBalanceResponse": {"clientId": `
+
+type Option func(*Server)
+
+func WithCredentials(username, password string) Option {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+func WithClientID(clientID string) Option {
+	return func(s *Server) {
+		s.clientID = clientID
+	}
+}
+
+// WithPublicKey enables signature verification; without it signatures are not checked.
+func WithPublicKey(publicKey *rsa.PublicKey) Option {
+	return func(s *Server) {
+		s.publicKey = publicKey
+	}
+}
+
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.tokenTTL = ttl
+	}
+}
+
+// WithClock replaces time.Now, e.g. to expire tokens without sleeping.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+func WithAccount(account Account) Option {
+	return func(s *Server) {
+		s.AddAccount(account)
+	}
+}
+
+func WithExternalAccount(account ExternalAccount) Option {
+	return func(s *Server) {
+		s.AddExternalAccount(account)
+	}
+}
+
+// Server is a simulated BNI gateway. It is safe for concurrent use.
+type Server struct {
+	URL string
+
+	server *httptest.Server
+
+	username  string
+	password  string
+	clientID  string
+	publicKey *rsa.PublicKey
+	tokenTTL  time.Duration
+	now       func() time.Time
+
+	mutex        sync.Mutex
+	tokens       map[string]time.Time
+	tokensIssued int
+	accounts     map[string]*Account
+	external     map[string]ExternalAccount
+	inquiries    map[string]inquiry
+	payments     map[string]*Payment
+	faults       []*Fault
+	seq          int64
+}
+
+// inquiry remembers an interbank inquiry so the payment can be matched to it.
+type inquiry struct {
+	accountNum string
+	bankCode   string
+	destAccNum string
+}
+
+// NewServer starts a simulator listening on a local address.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		username:  DefaultUsername,
+		password:  DefaultPassword,
+		clientID:  DefaultClientID,
+		tokenTTL:  DefaultTokenTTL,
+		now:       time.Now,
+		tokens:    map[string]time.Time{},
+		accounts:  map[string]*Account{},
+		external:  map[string]ExternalAccount{},
+		inquiries: map[string]inquiry{},
+		payments:  map[string]*Payment{},
+		seq:       950000,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+
+	return s
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Config returns a client config pointing at the simulator with matching
+// credentials. The signature config is left for the caller to fill in.
+func (s *Server) Config() config.Config {
+	return config.Config{
+		BNIServer: s.URL,
+		Username:  s.username,
+		Password:  s.password,
+		ClientID:  s.clientID,
+	}
+}
+
+// === state ===
+
+func (s *Server) AddAccount(account Account) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if account.Currency == "" {
+		account.Currency = "IDR"
+	}
+	if account.Status == "" {
+		account.Status = "Baik"
+	}
+	if account.Type == "" {
+		account.Type = "Giro"
+	}
+	s.accounts[account.Number] = &account
+}
+
+func (s *Server) AddExternalAccount(account ExternalAccount) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.external[externalKey(account.BankCode, account.Number)] = account
+}
+
+// Balance returns the current balance of a BNI account.
+func (s *Server) Balance(accountNo string) (int64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account, ok := s.accounts[accountNo]
+	if !ok {
+		return 0, false
+	}
+	return account.Balance, true
+}
+
+// Payment returns the payment recorded for crn.
+func (s *Server) Payment(crn string) (Payment, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payment, ok := s.payments[crn]
+	if !ok {
+		return Payment{}, false
+	}
+	return *payment, true
+}
+
+// TokensIssued counts successful token requests.
+func (s *Server) TokensIssued() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.tokensIssued
+}
+
+// ExpireTokens invalidates every issued token, the next API call gets a 401.
+func (s *Server) ExpireTokens() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens = map[string]time.Time{}
+}
+
+// === faults ===
+
+func (s *Server) InjectFault(fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = nil
+}
+
+// takeFault returns the first fault matching path and consumes one of its uses.
+func (s *Server) takeFault(path string) (Fault, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, fault := range s.faults {
+		if fault.Path != "" && fault.Path != path {
+			continue
+		}
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return *fault, true
+	}
+	return Fault{}, false
+}
+
+// === HTTP ===
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if fault, ok := s.takeFault(req.URL.Path); ok {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode != 0 {
+			if fault.ContentType != "" {
+				w.Header().Set("content-type", fault.ContentType)
+			}
+			w.WriteHeader(fault.StatusCode)
+			w.Write([]byte(fault.Body))
+			return
+		}
+	}
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if req.URL.Path == s.tokenPath() {
+		s.handleToken(w, req)
+		return
+	}
+
+	handle, ok := s.apiHandlers()[req.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !s.validToken(req.URL.Query().Get("access_token")) {
+		// BNI answers an invalid or expired token with an empty 401.
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	handle(w, req)
+}
+
+func (s *Server) tokenPath() string {
+	return config.DefaultAuthPath
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if !ok || username != s.username || password != s.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if err := req.ParseForm(); err != nil || req.Form.Get("grant_type") != "client_credentials" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	token := shortuuid.New()
+	s.tokens[token] = s.now().Add(s.tokenTTL)
+	s.tokensIssued++
+
+	writeJSON(w, tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.tokenTTL / time.Second),
+		Scope:       "resource.WRITE resource.READ",
+	})
+}
+
+func (s *Server) validToken(token string) bool {
+	expiry, ok := s.tokens[token]
+	if !ok {
+		return false
+	}
+	if !s.now().Before(expiry) {
+		delete(s.tokens, token)
+		return false
+	}
+	return true
+}
+
+// tokenResponse mirrors the token endpoint of BNI, which spells the lifetime expires_in.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
@@ -0,0 +1,221 @@
+package bnitest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/signature"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const privateKeyPath = "../testdata/id_rsa.pem"
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *bni.BNI) {
+	privateKey, err := signature.LoadPrivateKey(privateKeyPath)
+	if !util.AssertErrNil(t, err) {
+		t.FailNow()
+	}
+
+	opts = append([]Option{
+		WithPublicKey(&privateKey.PublicKey),
+		WithAccount(Account{Number: "113183203", Name: "FUNDEX", Balance: 1000000}),
+		WithAccount(Account{Number: "115471119", Name: "BENEFICIARY", Balance: 0}),
+		WithExternalAccount(ExternalAccount{BankCode: "014", BankName: "BCA", Number: "3333333333", Name: "DESTINATION"}),
+	}, opts...)
+	srv := NewServer(opts...)
+
+	cfg := srv.Config()
+	cfg.SignatureConfig = config.SignatureConfig{PrivateKeyPath: privateKeyPath}
+
+	return srv, bni.New(cfg)
+}
+
+func TestServer_Balance(t *testing.T) {
+	srv, client := newTestServer(t)
+	defer srv.Close()
+
+	resp, err := client.GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "113183203"})
+	if util.AssertErrNil(t, err) {
+		assert.Equal(t, RCSuccess, resp.Parameters.ResponseCode)
+		assert.Equal(t, int64(1000000), resp.Parameters.AccountBalance)
+		assert.Equal(t, "FUNDEX", resp.Parameters.CustomerName)
+	}
+	assert.Equal(t, 1, srv.TokensIssued())
+
+	_, err = client.GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "999"})
+	assert.Equal(t, bni.BadResponseError, errors.Cause(err))
+}
+
+func TestServer_DoPayment(t *testing.T) {
+	srv, client := newTestServer(t)
+	defer srv.Close()
+
+	req := dto.DoPaymentRequest{
+		CustomerReferenceNumber: "20170227000000000020",
+		PaymentMethod:           "0",
+		DebitAccountNo:          "113183203",
+		CreditAccountNo:         "115471119",
+		ValueCurrency:           "IDR",
+		ValueAmount:             "100500",
+	}
+
+	first := req
+	resp, err := client.DoPayment(context.Background(), &first)
+	if util.AssertErrNil(t, err) {
+		assert.Equal(t, RCSuccess, resp.Parameters.ResponseCode)
+		assert.NotZero(t, resp.Parameters.BankReference)
+	}
+
+	debit, _ := srv.Balance("113183203")
+	credit, _ := srv.Balance("115471119")
+	assert.Equal(t, int64(1000000-100500), debit)
+	assert.Equal(t, int64(100500), credit)
+
+	t.Run("duplicate CRN", func(t *testing.T) {
+		again := req
+		_, err := client.DoPayment(context.Background(), &again)
+		assert.Equal(t, bni.BadResponseError, errors.Cause(err))
+
+		debit, _ := srv.Balance("113183203")
+		assert.Equal(t, int64(1000000-100500), debit)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		status, err := client.GetPaymentStatus(context.Background(), &dto.GetPaymentStatusRequest{
+			CustomerReferenceNumber: req.CustomerReferenceNumber,
+		})
+		if util.AssertErrNil(t, err) {
+			assert.Equal(t, "Y", status.Parameters.PreviousResponse.TransactionStatus)
+			assert.Equal(t, int64(100500), status.Parameters.PreviousResponse.ValueAmount)
+		}
+	})
+
+	t.Run("insufficient funds is recorded", func(t *testing.T) {
+		poor := req
+		poor.CustomerReferenceNumber = "20170227000000000021"
+		poor.ValueAmount = "999999999"
+		_, err := client.DoPayment(context.Background(), &poor)
+		util.AssertErrNotNil(t, err)
+
+		payment, ok := srv.Payment(poor.CustomerReferenceNumber)
+		assert.True(t, ok)
+		assert.Equal(t, RCInsufficientFunds, payment.ResponseCode)
+	})
+}
+
+func TestServer_InterBank(t *testing.T) {
+	srv, client := newTestServer(t)
+	defer srv.Close()
+
+	inq, err := client.GetInterBankInquiry(context.Background(), &dto.GetInterBankInquiryRequest{
+		CustomerReferenceNumber: "1001",
+		AccountNum:              "113183203",
+		DestinationBankCode:     "014",
+		DestinationAccountNum:   "3333333333",
+	})
+	if !util.AssertErrNil(t, err) {
+		return
+	}
+	assert.Equal(t, "DESTINATION", inq.Parameters.DestinationAccountName)
+
+	payReq := dto.GetInterBankPaymentRequest{
+		CustomerReferenceNumber: "1002",
+		Amount:                  "50000",
+		AccountNum:              "113183203",
+		DestinationBankCode:     "014",
+		DestinationAccountNum:   "3333333333",
+		RetrievalReffNum:        inq.Parameters.RetrievalReffNum.String(),
+	}
+	first := payReq
+	_, err = client.GetInterBankPayment(context.Background(), &first)
+	util.AssertErrNil(t, err)
+
+	balance, _ := srv.Balance("113183203")
+	assert.Equal(t, int64(950000), balance)
+
+	// the retrieval reference is single use
+	reused := payReq
+	reused.CustomerReferenceNumber = "1003"
+	_, err = client.GetInterBankPayment(context.Background(), &reused)
+	assert.Equal(t, bni.BadResponseError, errors.Cause(err))
+}
+
+func TestServer_Signature(t *testing.T) {
+	srv, _ := newTestServer(t)
+	defer srv.Close()
+
+	// a client signing with no key configured gets rejected by signature verification
+	cfg := srv.Config()
+	cfg.SignatureConfig = config.SignatureConfig{PrivateKeyPath: "missing.pem"}
+	_, err := bni.New(cfg).GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "113183203"})
+	util.AssertErrNotNil(t, err)
+
+	cfg = srv.Config()
+	cfg.ClientID = "OTHER"
+	cfg.SignatureConfig = config.SignatureConfig{PrivateKeyPath: privateKeyPath}
+	_, err = bni.New(cfg).GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "113183203"})
+	assert.Equal(t, bni.BadResponseError, errors.Cause(err))
+}
+
+func TestServer_TokenExpiry(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv, client := newTestServer(t, WithTokenTTL(time.Minute), WithClock(func() time.Time { return now }))
+	defer srv.Close()
+
+	req := &dto.GetBalanceRequest{AccountNo: "113183203"}
+	_, err := client.GetBalance(context.Background(), req)
+	util.AssertErrNil(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = client.GetBalance(context.Background(), req)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, 2, srv.TokensIssued())
+}
+
+func TestServer_Faults(t *testing.T) {
+	srv, client := newTestServer(t)
+	defer srv.Close()
+
+	req := &dto.GetBalanceRequest{AccountNo: "113183203"}
+
+	t.Run("401 triggers re-authentication", func(t *testing.T) {
+		srv.InjectFault(Fault{Path: bni.BalancePath, StatusCode: http.StatusUnauthorized, Count: 1})
+		_, err := client.GetBalance(context.Background(), req)
+		util.AssertErrNil(t, err)
+	})
+
+	t.Run("5xx", func(t *testing.T) {
+		srv.InjectFault(Fault{Path: bni.BalancePath, StatusCode: http.StatusBadGateway,
+			ContentType: "text/html", Body: "<html>Bad Gateway</html>", Count: 1})
+		_, err := client.GetBalance(context.Background(), req)
+
+		httpErr, ok := errors.Cause(err).(*bni.HTTPError)
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		srv.InjectFault(Fault{Path: bni.BalancePath, StatusCode: http.StatusOK,
+			ContentType: "application/json", Body: MalformedJSON})
+		defer srv.ClearFaults()
+
+		_, err := client.GetBalance(context.Background(), req)
+		util.AssertErrNotNil(t, err)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		srv.InjectFault(Fault{Latency: 50 * time.Millisecond, Count: 1})
+		start := time.Now()
+		_, err := client.GetBalance(context.Background(), req)
+		util.AssertErrNil(t, err)
+		assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	})
+}
@@ -0,0 +1,304 @@
+// Package cassette records real HTTP exchanges with BNI into files and replays
+// them later, so tests catch sandbox behaviour changes without hitting the network.
+//
+//	rec, err := cassette.New("testdata/cassettes/balance.json", cassette.Replay)
+//	client := bni.New(cfg, bni.WithHTTPClient(rec.Client()))
+//	...
+//	err = rec.Stop() // writes the file in Record mode
+//
+// Credentials, access tokens and signatures are scrubbed before anything is
+// written, and are ignored when matching a request during replay.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fundex-id/bni-api-mgmt/redact"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/juju/errors"
+)
+
+// Mode selects whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// Replay serves responses from the cassette file and never touches the network.
+	Replay Mode = iota
+	// Record forwards requests to the real transport and captures them.
+	Record
+)
+
+// ErrInteractionNotFound is returned in Replay mode for a request the cassette does not hold.
+var ErrInteractionNotFound = errors.New("cassette: no recorded interaction matches the request")
+
+// Scrubbed replaces secret header and query values.
+const Scrubbed = redact.Redacted
+
+var (
+	defaultScrubHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	defaultScrubQuery   = []string{"access_token"}
+	defaultScrubFields  = []string{"signature", "access_token"}
+)
+
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the file format, interactions are kept in the order they happened.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Option func(*Recorder)
+
+// WithTransport sets the transport used in Record mode, cleanhttp's pooled transport by default.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithScrubHeaders scrubs more headers on top of Authorization and cookies.
+func WithScrubHeaders(names ...string) Option {
+	return func(r *Recorder) {
+		r.scrubHeaders = append(r.scrubHeaders, names...)
+	}
+}
+
+// WithScrubFields scrubs more JSON body fields on top of signature and access_token.
+func WithScrubFields(fields ...string) Option {
+	return func(r *Recorder) {
+		policies := map[string]redact.Policy{}
+		for _, field := range fields {
+			policies[field] = redact.Drop
+		}
+		r.redactor = r.redactor.With(policies)
+	}
+}
+
+// Recorder is an http.RoundTripper that records to or replays from a cassette file.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	scrubHeaders []string
+	redactor     *redact.Redactor
+
+	mutex    sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// New loads path in Replay mode, or starts an empty cassette in Record mode.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	policies := map[string]redact.Policy{}
+	for _, field := range defaultScrubFields {
+		policies[field] = redact.Drop
+	}
+
+	r := &Recorder{
+		path:         path,
+		mode:         mode,
+		transport:    cleanhttp.DefaultPooledTransport(),
+		scrubHeaders: append([]string(nil), defaultScrubHeaders...),
+		redactor:     redact.New(policies),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode == Replay {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := json.Unmarshal(raw, &r.cassette); err != nil {
+			return nil, errors.Annotatef(err, "cassette %s", path)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Client returns an http.Client going through the recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns a copy of what was recorded or loaded.
+func (r *Recorder) Interactions() []Interaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Stop writes the cassette in Record mode, it is a no-op in Replay mode.
+func (r *Recorder) Stop() error {
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	raw, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(r.path, append(raw, '\n'), 0644))
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	scrubbedReq := r.scrubRequest(req, reqBody)
+
+	if r.mode == Replay {
+		return r.replay(req, scrubbedReq)
+	}
+
+	forward := req.Clone(req.Context())
+	forward.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	resp, err := r.transport.RoundTrip(forward)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.mutex.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: scrubbedReq,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.scrubHeader(resp.Header),
+			Body:       string(r.redactor.RedactJSON(respBody)),
+		},
+	})
+	r.mutex.Unlock()
+
+	return resp, nil
+}
+
+// replay serves the first unused interaction matching req, so repeated
+// identical requests get their responses in recorded order.
+func (r *Recorder) replay(req *http.Request, scrubbed Request) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !matches(interaction.Request, scrubbed) {
+			continue
+		}
+		r.used[i] = true
+
+		recorded := interaction.Response
+		return &http.Response{
+			Status:        http.StatusText(recorded.StatusCode),
+			StatusCode:    recorded.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        recorded.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewBufferString(recorded.Body)),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, errors.Annotatef(ErrInteractionNotFound, "%s %s", scrubbed.Method, scrubbed.URL)
+}
+
+// matches compares method, path, scrubbed query and scrubbed body, the host is
+// ignored so a cassette recorded against the sandbox replays against any URL.
+func matches(recorded, req Request) bool {
+	if recorded.Method != req.Method || recorded.Body != req.Body {
+		return false
+	}
+
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	reqURL, err := url.Parse(req.URL)
+	if err != nil {
+		return false
+	}
+	return recordedURL.Path == reqURL.Path && recordedURL.RawQuery == reqURL.RawQuery
+}
+
+func (r *Recorder) scrubRequest(req *http.Request, body []byte) Request {
+	u := *req.URL
+	query := u.Query()
+	for _, name := range defaultScrubQuery {
+		if _, ok := query[name]; ok {
+			query.Set(name, Scrubbed)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return Request{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: r.scrubHeader(req.Header),
+		Body:   string(r.redactor.RedactJSON(body)),
+	}
+}
+
+func (r *Recorder) scrubHeader(header http.Header) http.Header {
+	scrubbed := header.Clone()
+	for _, name := range r.scrubHeaders {
+		if scrubbed.Get(name) != "" {
+			scrubbed.Set(name, Scrubbed)
+		}
+	}
+	// tracing headers differ on every run and only add noise
+	scrubbed.Del("Traceparent")
+	scrubbed.Del("Tracestate")
+	return scrubbed
+}
+
+// readBody drains and closes body, which may be nil.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
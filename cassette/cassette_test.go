@@ -0,0 +1,66 @@
+package cassette
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/bnitest"
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder_RecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "balance.json")
+	signatureConfig := config.SignatureConfig{PrivateKeyPath: "../testdata/id_rsa.pem"}
+
+	srv := bnitest.NewServer(bnitest.WithAccount(bnitest.Account{Number: "113183203", Name: "FUNDEX", Balance: 1000}))
+	cfg := srv.Config()
+	cfg.SignatureConfig = signatureConfig
+
+	rec, err := New(path, Record)
+	util.AssertErrNil(t, err)
+
+	client := bni.New(cfg, bni.WithHTTPClient(rec.Client()))
+	recorded, err := client.GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "113183203"})
+	util.AssertErrNil(t, err)
+	util.AssertErrNil(t, rec.Stop())
+	srv.Close()
+
+	// 401, token, retried balance
+	assert.Len(t, rec.Interactions(), 3)
+
+	raw, err := ioutil.ReadFile(path)
+	util.AssertErrNil(t, err)
+	assert.NotContains(t, string(raw), "Basic ")
+	for _, interaction := range rec.Interactions() {
+		if interaction.Request.Method == "POST" && strings.Contains(interaction.Request.URL, bni.BalancePath) {
+			assert.Contains(t, interaction.Request.URL, "access_token=%5BREDACTED%5D")
+			assert.Contains(t, interaction.Request.Body, `"signature":"`+Scrubbed+`"`)
+		}
+		if strings.Contains(interaction.Request.URL, config.DefaultAuthPath) {
+			assert.Contains(t, interaction.Response.Body, `"access_token":"`+Scrubbed+`"`)
+		}
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		rec, err := New(path, Replay)
+		util.AssertErrNil(t, err)
+
+		client := bni.New(cfg, bni.WithHTTPClient(rec.Client()))
+		replayed, err := client.GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "113183203"})
+		if util.AssertErrNil(t, err) {
+			assert.Equal(t, recorded.Parameters.AccountBalance, replayed.Parameters.AccountBalance)
+		}
+
+		// every interaction is used once, a new request can't be served
+		_, err = client.GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "113183203"})
+		assert.True(t, strings.Contains(errors.Details(err), ErrInteractionNotFound.Error()))
+	})
+}
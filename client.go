@@ -0,0 +1,24 @@
+package bni
+
+import (
+	"context"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+)
+
+// Client is the set of BNI operations. Depend on it rather than on *BNI so
+// tests can use bnifake.Fake instead of a real gateway.
+type Client interface {
+	DoAuthentication(ctx context.Context) (*dto.GetTokenResponse, error)
+	GetBalance(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*dto.GetBalanceResponse, error)
+	GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error)
+	DoPayment(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error)
+	GetPaymentStatus(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error)
+	GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error)
+	GetInterBankPayment(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (*dto.GetInterBankPaymentResponse, error)
+}
+
+var (
+	_ Client = (*BNI)(nil)
+	_ Client = (*Registry)(nil)
+)
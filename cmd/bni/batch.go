@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fundex-id/bni-api-mgmt/batch"
+	"github.com/juju/errors"
+)
+
+func runBatch(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	in := flags.String("in", "", "payments file, .csv or .json")
+	mappingPath := flags.String("mapping", "", "YAML or JSON file mapping fields to column names")
+	out := flags.String("out", "", "results file, .csv or .json; CSV on stdout when empty")
+	workers := flags.Int("workers", 1, "payments in flight at once")
+	rate := flags.Float64("rate", 0, "max requests per second, 0 for no limit")
+	checkpoint := flags.String("checkpoint", "", "file recording finished payments, to resume an interrupted run")
+	confirm := flags.Bool("confirm", false, "actually send the payments")
+	if err := e.parse(flags, args, "in"); err != nil {
+		return err
+	}
+
+	mapping := batch.DefaultMapping()
+	if *mappingPath != "" {
+		var err error
+		if mapping, err = batch.LoadMapping(*mappingPath); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	instructions, err := batch.ReadFile(*in, mapping)
+	if rowErrs, ok := errors.Cause(err).(batch.RowErrors); ok {
+		for _, rowErr := range rowErrs {
+			fmt.Fprintf(e.stderr, "bni %s: %s\n", e.cmdName, rowErr)
+		}
+		return errors.Errorf("%d invalid rows in %s, nothing sent", len(rowErrs), *in)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	total, err := batch.Total(instructions)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := e.requireConfirm(*confirm, fmt.Sprintf("send %d payments totalling %d.%02d from %s",
+		len(instructions), total/100, total%100, *in)); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	opts := []batch.Option{batch.WithWorkers(*workers), batch.WithRateLimit(*rate)}
+	if *checkpoint != "" {
+		opts = append(opts, batch.WithCheckpoint(*checkpoint))
+	}
+	report, err := batch.New(client, opts...).Run(ctx, instructions)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if *out == "" {
+		err = batch.WriteCSV(e.out.w, report)
+	} else {
+		err = batch.WriteFile(*out, report)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	fmt.Fprintf(e.stderr, "bni %s: %d succeeded, %d failed, %d unknown\n",
+		e.cmdName, report.Succeeded, report.Failed, report.Unknown)
+	if report.Failed > 0 || report.Unknown > 0 {
+		// make scripts notice, the results are written either way
+		return errors.New("not every payment succeeded")
+	}
+	return nil
+}
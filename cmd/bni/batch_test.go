@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_Batch(t *testing.T) {
+	srv, bni := newTestCLI(t)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "payouts.csv")
+	util.AssertErrNil(t, ioutil.WriteFile(in, []byte(`crn,debitAccount,creditAccount,amount
+20170227000000000020,113183203,115471119,100500
+20170227000000000021,113183203,999999999,1000.50
+`), 0600))
+	out := filepath.Join(dir, "results.csv")
+
+	code, _, stderr := bni("batch", "-in", in, "-out", out)
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "send 2 payments totalling 101500.50")
+	_, sent := srv.Payment("20170227000000000020")
+	assert.False(t, sent)
+
+	code, _, stderr = bni("batch", "-in", in, "-out", out, "-confirm")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "1 succeeded, 1 failed, 0 unknown")
+
+	results, err := ioutil.ReadFile(out)
+	util.AssertErrNil(t, err)
+	assert.Regexp(t, `(?m)^20170227000000000020,20170227000000000020,SUCCESS,\d+,0001,`, string(results))
+	assert.Regexp(t, `(?m)^20170227000000000021,20170227000000000021,FAILED,,0100,Request has been failed,`, string(results))
+
+	util.AssertErrNil(t, ioutil.WriteFile(in, []byte("crn,debitAccount,creditAccount,amount\n1,113183203,115471119,-5\n"), 0600))
+	code, _, stderr = bni("batch", "-in", in, "-confirm")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, `row 2: amount: "-5" is not a positive amount`)
+	assert.Contains(t, stderr, "nothing sent")
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+// newFlags returns the flag set of a command, with errors going to stderr.
+func (e *env) newFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("bni "+e.cmdName, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	return flags
+}
+
+// parse parses args and checks that every flag in required was given a value.
+func (e *env) parse(flags *flag.FlagSet, args []string, required ...string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(e.stderr, "bni %s: unexpected arguments %q\n", e.cmdName, flags.Args())
+		return errUsage
+	}
+
+	var missing []string
+	for _, name := range required {
+		if flags.Lookup(name).Value.String() == "" {
+			missing = append(missing, "-"+name)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(e.stderr, "bni %s: missing %s\n", e.cmdName, strings.Join(missing, ", "))
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// requireConfirm refuses a money-moving command unless -confirm was given.
+func (e *env) requireConfirm(confirm bool, summary string) error {
+	if confirm {
+		return nil
+	}
+	fmt.Fprintf(e.stderr, "bni %s: would %s\nbni %s: re-run with -confirm to send it\n", e.cmdName, summary, e.cmdName)
+	return errUsage
+}
+
+func runAuth(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	if err := e.parse(flags, args); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.DoAuthentication(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// only tell whether it worked, the token itself is a secret
+	return e.out.print(map[string]interface{}{
+		"tokenType": resp.TokenType,
+		"scope":     resp.Scope,
+		"server":    e.config.ServerURL(),
+	})
+}
+
+func runBalance(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	account := flags.String("account", "", "account number")
+	if err := e.parse(flags, args, "account"); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.GetBalance(ctx, &dto.GetBalanceRequest{AccountNo: *account})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.out.print(resp)
+}
+
+func runInquiry(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	account := flags.String("account", "", "account number")
+	if err := e.parse(flags, args, "account"); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.GetInHouseInquiry(ctx, &dto.GetInHouseInquiryRequest{AccountNo: *account})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.out.print(resp)
+}
+
+func runInterBankInquiry(ctx context.Context, e *env, args []string) error {
+	var req dto.GetInterBankInquiryRequest
+	flags := e.newFlags()
+	flags.StringVar(&req.AccountNum, "account", "", "source account number")
+	flags.StringVar(&req.DestinationBankCode, "bank", "", "destination bank code")
+	flags.StringVar(&req.DestinationAccountNum, "dest", "", "destination account number")
+	flags.StringVar(&req.CustomerReferenceNumber, "crn", "", "customer reference number")
+	if err := e.parse(flags, args, "account", "bank", "dest"); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.GetInterBankInquiry(ctx, &req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.out.print(resp)
+}
+
+func runPay(ctx context.Context, e *env, args []string) error {
+	var req dto.DoPaymentRequest
+	flags := e.newFlags()
+	flags.StringVar(&req.CustomerReferenceNumber, "crn", "", "customer reference number")
+	flags.StringVar(&req.PaymentMethod, "method", "0", "0 in-house, 1 RTGS, 2 clearing")
+	flags.StringVar(&req.DebitAccountNo, "debit", "", "debited account number")
+	flags.StringVar(&req.CreditAccountNo, "credit", "", "credited account number")
+	flags.StringVar(&req.ValueAmount, "amount", "", "amount")
+	flags.StringVar(&req.ValueCurrency, "currency", "IDR", "currency")
+	flags.StringVar(&req.ValueDate, "value-date", "", "value date as yyyyMMddHHmmssSSS, today in WIB when empty")
+	flags.StringVar(&req.Remark, "remark", "", "remark")
+	flags.StringVar(&req.DestinationBankCode, "bank", "", "destination bank code, for RTGS and clearing")
+	flags.StringVar(&req.BeneficiaryName, "name", "", "beneficiary name, for RTGS and clearing")
+	flags.StringVar(&req.BeneficiaryEmailAddress, "email", "", "beneficiary email address")
+	flags.StringVar(&req.ChargingModelId, "charging-model", "", "charging model id")
+	confirm := flags.Bool("confirm", false, "actually send the payment")
+	if err := e.parse(flags, args, "crn", "debit", "credit", "amount"); err != nil {
+		return err
+	}
+	if req.ValueDate == "" {
+		req.ValueDate = valueDate(time.Now())
+	}
+	if err := e.requireConfirm(*confirm, fmt.Sprintf("pay %s %s from %s to %s (CRN %s)",
+		req.ValueCurrency, req.ValueAmount, req.DebitAccountNo, req.CreditAccountNo, req.CustomerReferenceNumber)); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.DoPayment(ctx, &req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.out.print(resp)
+}
+
+func runInterBankPay(ctx context.Context, e *env, args []string) error {
+	var req dto.GetInterBankPaymentRequest
+	flags := e.newFlags()
+	flags.StringVar(&req.CustomerReferenceNumber, "crn", "", "customer reference number")
+	flags.StringVar(&req.AccountNum, "account", "", "source account number")
+	flags.StringVar(&req.DestinationBankCode, "bank", "", "destination bank code")
+	flags.StringVar(&req.DestinationBankName, "bank-name", "", "destination bank name")
+	flags.StringVar(&req.DestinationAccountNum, "dest", "", "destination account number")
+	flags.StringVar(&req.DestinationAccountName, "dest-name", "", "destination account name")
+	flags.StringVar(&req.Amount, "amount", "", "amount")
+	flags.StringVar(&req.RetrievalReffNum, "reff", "", "retrieval reference number from interbank-inquiry")
+	confirm := flags.Bool("confirm", false, "actually send the payment")
+	if err := e.parse(flags, args, "crn", "account", "bank", "dest", "amount", "reff"); err != nil {
+		return err
+	}
+	if err := e.requireConfirm(*confirm, fmt.Sprintf("pay %s from %s to %s at bank %s (CRN %s)",
+		req.Amount, req.AccountNum, req.DestinationAccountNum, req.DestinationBankCode, req.CustomerReferenceNumber)); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.GetInterBankPayment(ctx, &req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.out.print(resp)
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	var req dto.GetPaymentStatusRequest
+	flags := e.newFlags()
+	flags.StringVar(&req.CustomerReferenceNumber, "crn", "", "customer reference number")
+	if err := e.parse(flags, args, "crn"); err != nil {
+		return err
+	}
+
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.GetPaymentStatus(ctx, &req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return e.out.print(resp)
+}
+
+// wib is the time zone BNI works in.
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// valueDate is midnight of the WIB day of now, in the form of BNI's examples.
+func valueDate(now time.Time) string {
+	return now.In(wib).Format("20060102") + "000000000"
+}
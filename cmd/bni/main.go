@@ -0,0 +1,170 @@
+// Command bni runs single BNI H2H operations from the shell, for operations
+// staff checking a balance or chasing a payment.
+//
+//	bni -config bni.yaml balance -account 113183203
+//	bni -json status -crn 20170227000000000020
+//	bni pay -crn 20170227000000000020 -debit 113183203 -credit 115471119 -amount 100500 -confirm
+//
+// Config is read from the -config file (YAML or JSON) and then from BNI_*
+// env vars, which override the file. Money-moving commands refuse to run
+// without -confirm.
+//
+// The batch command sends a payments file and writes one result row per
+// payment; raise -timeout to fit the whole file:
+//
+//	bni -timeout 1h batch -in payouts.csv -out results.csv -workers 4 -checkpoint payouts.ckpt -confirm
+//
+// The keygen, pubkey, sign and verify commands work offline on keys and
+// request files, to debug "invalid signature" answers with BNI:
+//
+//	bni keygen -out bni.pem
+//	bni sign -key bni.pem -op balance -request balance.json
+//	bni verify -pub bni.pem.pub -op balance -request signed.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/juju/errors"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// command is one subcommand; run parses its own flags from args.
+type command struct {
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+// env carries what the global flags set up for a command.
+type env struct {
+	config  config.Config
+	client  func() (*bni.BNI, error)
+	out     *printer
+	stderr  io.Writer
+	cmdName string
+}
+
+var commands = map[string]command{
+	"auth":              {"request an access token to check credentials", runAuth},
+	"balance":           {"show the balance of an account", runBalance},
+	"inquiry":           {"show the holder and status of a BNI account", runInquiry},
+	"interbank-inquiry": {"look up an account at another bank", runInterBankInquiry},
+	"pay":               {"transfer money with DoPayment (needs -confirm)", runPay},
+	"interbank-pay":     {"transfer money to another bank (needs -confirm)", runInterBankPay},
+	"status":            {"show the status of a payment by CRN", runStatus},
+	"batch":             {"send the payments of a CSV or JSON file (needs -confirm)", runBatch},
+
+	"keygen": {"generate an RSA keypair for request signing", runKeygen},
+	"pubkey": {"print the public key of a private key, for registration with BNI", runPubkey},
+	"sign":   {"show the canonical string and signature of a request JSON", runSign},
+	"verify": {"check the signature of a request JSON against a public key", runVerify},
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	os.Exit(code)
+}
+
+// errUsage is returned by commands when their flags are wrong, the message has been printed already.
+var errUsage = errors.New("usage")
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bni", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "YAML or JSON config file")
+	envPrefix := flags.String("env-prefix", "BNI_", "prefix of config env vars, they override the file")
+	jsonOutput := flags.Bool("json", false, "print responses as JSON")
+	timeout := flags.Duration("timeout", time.Minute, "give up after this long")
+	flags.Usage = func() { usage(flags) }
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		usage(flags)
+		return exitUsage
+	}
+
+	name := flags.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "bni: unknown command %q\n\n", name)
+		usage(flags)
+		return exitUsage
+	}
+
+	e := &env{
+		out:     &printer{w: stdout, json: *jsonOutput},
+		stderr:  stderr,
+		cmdName: name,
+	}
+	e.client = func() (*bni.BNI, error) {
+		sources := []config.Source{}
+		if *configPath != "" {
+			sources = append(sources, config.FromFile(*configPath))
+		}
+		sources = append(sources, config.FromEnv(*envPrefix))
+
+		cfg, err := config.Load(sources...)
+		if err != nil {
+			return nil, err
+		}
+		e.config = cfg
+		return bni.New(cfg), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	err := cmd.run(ctx, e, flags.Args()[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Cause(err) == errUsage:
+		return exitUsage
+	default:
+		fmt.Fprintf(stderr, "bni %s: %v\n", name, err)
+		return exitError
+	}
+}
+
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintf(w, "Usage: bni [flags] <command> [command flags]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-18s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintf(w, "\nFlags:\n")
+	flags.PrintDefaults()
+	fmt.Fprintf(w, "\nRun 'bni <command> -h' for the flags of a command.\n")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/bnitest"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCLI(t *testing.T) (*bnitest.Server, func(args ...string) (int, string, string)) {
+	srv := bnitest.NewServer(
+		bnitest.WithAccount(bnitest.Account{Number: "113183203", Name: "FUNDEX", Balance: 1000000}),
+		bnitest.WithAccount(bnitest.Account{Number: "115471119", Name: "BENEFICIARY"}),
+	)
+
+	privateKeyPath, err := filepath.Abs("../../testdata/id_rsa.pem")
+	util.AssertErrNil(t, err)
+
+	cfg := srv.Config()
+	cfg.PrivateKeyPath = privateKeyPath
+	raw, err := json.Marshal(cfg)
+	util.AssertErrNil(t, err)
+
+	configPath := filepath.Join(t.TempDir(), "bni.json")
+	util.AssertErrNil(t, ioutil.WriteFile(configPath, raw, 0600))
+
+	return srv, func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-config", configPath, "-env-prefix", "BNI_CLI_TEST_"}, args...)
+		code := run(context.Background(), args, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+}
+
+func TestRun_Balance(t *testing.T) {
+	srv, bni := newTestCLI(t)
+	defer srv.Close()
+
+	code, stdout, _ := bni("balance", "-account", "113183203")
+	assert.Equal(t, exitOK, code)
+	assert.Regexp(t, `accountBalance\s+1000000`, stdout)
+	assert.Regexp(t, `responseCode\s+0001`, stdout)
+
+	code, stdout, _ = bni("-json", "balance", "-account", "113183203")
+	assert.Equal(t, exitOK, code)
+	var resp map[string]interface{}
+	util.AssertErrNil(t, json.Unmarshal([]byte(stdout), &resp))
+	assert.Contains(t, resp, "parameters")
+
+	code, _, stderr := bni("balance")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "missing -account")
+}
+
+func TestRun_PayNeedsConfirm(t *testing.T) {
+	srv, bni := newTestCLI(t)
+	defer srv.Close()
+
+	args := []string{"pay", "-crn", "20170227000000000020", "-debit", "113183203", "-credit", "115471119", "-amount", "100500"}
+
+	code, _, stderr := bni(args...)
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-confirm")
+	_, sent := srv.Payment("20170227000000000020")
+	assert.False(t, sent)
+
+	code, stdout, stderr := bni(append(args, "-confirm")...)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Regexp(t, `bankReference\s+\d+`, stdout)
+
+	code, stdout, _ = bni("status", "-crn", "20170227000000000020")
+	assert.Equal(t, exitOK, code)
+	assert.Regexp(t, `previousResponse.transactionStatus\s+Y`, stdout)
+}
+
+func TestRun_Errors(t *testing.T) {
+	srv, bni := newTestCLI(t)
+	defer srv.Close()
+
+	code, _, stderr := bni("nope")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, `unknown command "nope"`)
+
+	code, _, stderr = bni("status", "-crn", "404")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "bni status:")
+}
+
+func TestValueDate(t *testing.T) {
+	// 18:30 UTC is already the next day in WIB
+	assert.Equal(t, "20170228000000000", valueDate(time.Date(2017, 2, 27, 18, 30, 0, 0, time.UTC)))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+)
+
+// printer writes a response either as indented JSON or as aligned
+// "field  value" lines, nested fields joined with dots.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func (p *printer) print(v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if p.json {
+		_, err := fmt.Fprintf(p.w, "%s\n", raw)
+		return errors.Trace(err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return errors.Trace(err)
+	}
+
+	fields := map[string]string{}
+	flatten("", tree, fields)
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", name, fields[name])
+	}
+	return errors.Trace(tw.Flush())
+}
+
+// flatten collects the scalar leaves of node, "parameters.responseCode" style.
+// The "parameters." prefix every BNI response has is left out.
+func flatten(prefix string, node interface{}, fields map[string]string) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			name := key
+			if prefix != "" && prefix != "parameters" {
+				name = prefix + "." + key
+			}
+			flatten(name, child, fields)
+		}
+	case []interface{}:
+		for i, child := range value {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, fields)
+		}
+	case nil:
+	default:
+		fields[prefix] = fmt.Sprint(value)
+	}
+}
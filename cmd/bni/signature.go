@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/signature"
+	"github.com/juju/errors"
+)
+
+// signables maps the -op of sign and verify to the request it decodes,
+// named after the command sending that request.
+var signables = map[string]func() dto.Signable{
+	"balance":           func() dto.Signable { return &dto.GetBalanceRequest{} },
+	"inquiry":           func() dto.Signable { return &dto.GetInHouseInquiryRequest{} },
+	"pay":               func() dto.Signable { return &dto.DoPaymentRequest{} },
+	"status":            func() dto.Signable { return &dto.GetPaymentStatusRequest{} },
+	"interbank-inquiry": func() dto.Signable { return &dto.GetInterBankInquiryRequest{} },
+	"interbank-pay":     func() dto.Signable { return &dto.GetInterBankPaymentRequest{} },
+}
+
+func signableNames() string {
+	names := make([]string, 0, len(signables))
+	for name := range signables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func runKeygen(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	out := flags.String("out", "", "private key file, the public key goes to <out>.pub")
+	bits := flags.Int("bits", signature.MinKeyBits, "key size, at least 2048")
+	if err := e.parse(flags, args, "out"); err != nil {
+		return err
+	}
+
+	privateKey, err := signature.GenerateKey(*bits)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	publicPEM, err := signature.EncodePublicKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// O_EXCL: never overwrite a key that may already be registered with BNI
+	if err := writeNewFile(*out, signature.EncodePrivateKeyPEM(privateKey), 0600); err != nil {
+		return err
+	}
+	if err := writeNewFile(*out+".pub", publicPEM, 0644); err != nil {
+		return err
+	}
+
+	return e.out.print(map[string]interface{}{
+		"privateKey": *out,
+		"publicKey":  *out + ".pub",
+	})
+}
+
+func runPubkey(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	key := flags.String("key", "", "private key file")
+	if err := e.parse(flags, args, "key"); err != nil {
+		return err
+	}
+
+	privateKey, err := signature.LoadPrivateKey(*key)
+	if err != nil {
+		return errors.Annotate(err, *key)
+	}
+	publicPEM, err := signature.EncodePublicKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// always raw PEM, it is meant to be pasted into BNI's registration form
+	_, err = e.out.w.Write(publicPEM)
+	return errors.Trace(err)
+}
+
+func runSign(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	key := flags.String("key", "", "private key file")
+	op := flags.String("op", "", "request type: "+signableNames())
+	request := flags.String("request", "", "request JSON file, - for stdin")
+	clientID := flags.String("client-id", "", "client id, overrides the one in the request")
+	if err := e.parse(flags, args, "key", "op", "request"); err != nil {
+		return err
+	}
+
+	req, common, err := readSignable(*op, *request, *clientID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := signature.LoadPrivateKey(*key)
+	if err != nil {
+		return errors.Annotate(err, *key)
+	}
+
+	data := req.SignatureData()
+	sig, err := signature.SignWithKey(privateKey, data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	common.Signature = sig
+
+	return e.out.print(map[string]interface{}{
+		"canonicalString": data,
+		"signature":       sig,
+		"request":         req,
+	})
+}
+
+func runVerify(ctx context.Context, e *env, args []string) error {
+	flags := e.newFlags()
+	pub := flags.String("pub", "", "public key file")
+	key := flags.String("key", "", "private key file, instead of -pub")
+	op := flags.String("op", "", "request type: "+signableNames())
+	request := flags.String("request", "", "request JSON file, - for stdin")
+	sig := flags.String("signature", "", "signature to check, the request's own when empty")
+	if err := e.parse(flags, args, "op", "request"); err != nil {
+		return err
+	}
+	if (*pub == "") == (*key == "") {
+		fmt.Fprintf(e.stderr, "bni %s: exactly one of -pub or -key is required\n", e.cmdName)
+		return errUsage
+	}
+
+	req, common, err := readSignable(*op, *request, "")
+	if err != nil {
+		return err
+	}
+	if *sig == "" {
+		*sig = common.Signature
+	}
+	if *sig == "" {
+		return errors.New("no -signature and the request has no signature field")
+	}
+
+	var publicKey *rsa.PublicKey
+	if *pub != "" {
+		publicKey, err = signature.LoadPublicKey(*pub)
+		if err != nil {
+			return errors.Annotate(err, *pub)
+		}
+	} else {
+		privateKey, err := signature.LoadPrivateKey(*key)
+		if err != nil {
+			return errors.Annotate(err, *key)
+		}
+		publicKey = &privateKey.PublicKey
+	}
+
+	data := req.SignatureData()
+	if err := signature.Verify(publicKey, data, *sig); err != nil {
+		return errors.Annotatef(err, "signature does not match canonical string %q", data)
+	}
+
+	return e.out.print(map[string]interface{}{
+		"canonicalString": data,
+		"valid":           true,
+	})
+}
+
+// readSignable decodes the request of type op from path, strictly so a
+// misspelled field can't silently drop out of the canonical string.
+func readSignable(op, path, clientID string) (dto.Signable, *dto.CommonRequest, error) {
+	newSignable, ok := signables[op]
+	if !ok {
+		return nil, nil, errors.NotValidf("-op %q, want one of %s", op, signableNames())
+	}
+
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		raw, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	req := newSignable()
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return nil, nil, errors.Annotate(err, path)
+	}
+
+	common := commonRequest(req)
+	if clientID != "" {
+		common.ClientID = clientID
+	}
+	return req, common, nil
+}
+
+func commonRequest(req dto.Signable) *dto.CommonRequest {
+	switch r := req.(type) {
+	case *dto.GetBalanceRequest:
+		return &r.CommonRequest
+	case *dto.GetInHouseInquiryRequest:
+		return &r.CommonRequest
+	case *dto.DoPaymentRequest:
+		return &r.CommonRequest
+	case *dto.GetPaymentStatusRequest:
+		return &r.CommonRequest
+	case *dto.GetInterBankInquiryRequest:
+		return &r.CommonRequest
+	case *dto.GetInterBankPaymentRequest:
+		return &r.CommonRequest
+	}
+	panic(fmt.Sprintf("no CommonRequest in %T", req))
+}
+
+func writeNewFile(path string, content []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return errors.Trace(err)
+	}
+	return errors.Trace(file.Close())
+}
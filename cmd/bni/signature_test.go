@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_SignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "bni.pem")
+
+	code, _, stderr := runCLI("keygen", "-out", key, "-bits", "1024")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "at least 2048")
+
+	code, _, stderr = runCLI("keygen", "-out", key, "-bits", "2048")
+	assert.Equal(t, exitOK, code, stderr)
+
+	// an existing key is never overwritten
+	code, _, _ = runCLI("keygen", "-out", key)
+	assert.Equal(t, exitError, code)
+
+	code, stdout, _ := runCLI("pubkey", "-key", key)
+	assert.Equal(t, exitOK, code)
+	pub, err := ioutil.ReadFile(key + ".pub")
+	util.AssertErrNil(t, err)
+	assert.Equal(t, string(pub), stdout)
+
+	request := filepath.Join(dir, "balance.json")
+	util.AssertErrNil(t, ioutil.WriteFile(request, []byte(`{"accountNo": "113183203"}`), 0644))
+
+	code, stdout, stderr = runCLI("-json", "sign", "-key", key, "-op", "balance", "-request", request, "-client-id", "IDBNITEST")
+	assert.Equal(t, exitOK, code, stderr)
+
+	var signed struct {
+		CanonicalString string          `json:"canonicalString"`
+		Signature       string          `json:"signature"`
+		Request         json.RawMessage `json:"request"`
+	}
+	util.AssertErrNil(t, json.Unmarshal([]byte(stdout), &signed))
+	assert.Equal(t, "IDBNITEST113183203", signed.CanonicalString)
+
+	signedRequest := filepath.Join(dir, "signed.json")
+	util.AssertErrNil(t, ioutil.WriteFile(signedRequest, signed.Request, 0644))
+
+	code, stdout, stderr = runCLI("verify", "-pub", key+".pub", "-op", "balance", "-request", signedRequest)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Regexp(t, `valid\s+true`, stdout)
+
+	code, _, stderr = runCLI("verify", "-key", key, "-op", "balance", "-request", request, "-signature", signed.Signature)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, `canonical string "113183203"`)
+
+	code, _, _ = runCLI("sign", "-key", key, "-op", "balance", "-request", request, "-signature", "x")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestRun_SignRejectsUnknownFields(t *testing.T) {
+	request := filepath.Join(t.TempDir(), "pay.json")
+	util.AssertErrNil(t, ioutil.WriteFile(request, []byte(`{"debitAcountNo": "113183203"}`), 0644))
+
+	code, _, stderr := runCLI("sign", "-key", "../../testdata/id_rsa.pem", "-op", "pay", "-request", request)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "debitAcountNo")
+}
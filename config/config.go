@@ -1,14 +1,24 @@
 package config
 
 type Config struct {
-	Username  string
-	Password  string
-	ClientID  string
-	BNIServer string
-	LogPath   string
-	SignatureConfig
+	// Environment picks the BNI gateway, BNIServer and AuthPath override its preset.
+	Environment Environment `json:"environment,omitempty" yaml:"environment,omitempty" env:"ENVIRONMENT"`
+	// AllowProductionPayments must be set to send money-moving operations to production.
+	AllowProductionPayments bool `json:"allowProductionPayments,omitempty" yaml:"allowProductionPayments,omitempty" env:"ALLOW_PRODUCTION_PAYMENTS"`
+
+	Username  string `json:"username,omitempty" yaml:"username,omitempty" env:"USERNAME"`
+	Password  string `json:"password,omitempty" yaml:"password,omitempty" env:"PASSWORD"`
+	ClientID  string `json:"clientId,omitempty" yaml:"clientId,omitempty" env:"CLIENT_ID"`
+	BNIServer string `json:"bniServer,omitempty" yaml:"bniServer,omitempty" env:"BNI_SERVER"`
+	AuthPath  string `json:"authPath,omitempty" yaml:"authPath,omitempty" env:"AUTH_PATH"`
+	// LogPath enables logging to a rotated file when no logger is injected.
+	// Clients of one process logging to the same path share its writer.
+	LogPath string `json:"logPath,omitempty" yaml:"logPath,omitempty" env:"LOG_PATH"`
+	// LogLevel is the minimum level written to LogPath, "info" when empty.
+	LogLevel        string `json:"logLevel,omitempty" yaml:"logLevel,omitempty" env:"LOG_LEVEL"`
+	SignatureConfig `yaml:",inline"`
 }
 
 type SignatureConfig struct {
-	PrivateKeyPath string
+	PrivateKeyPath string `json:"privateKeyPath,omitempty" yaml:"privateKeyPath,omitempty" env:"PRIVATE_KEY_PATH"`
 }
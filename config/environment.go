@@ -0,0 +1,87 @@
+package config
+
+import (
+	"crypto/tls"
+)
+
+// Environment selects the BNI gateway a client talks to.
+type Environment string
+
+const (
+	// Custom uses BNIServer as is, this is what an empty Environment means.
+	Custom     Environment = "custom"
+	Sandbox    Environment = "sandbox"
+	Production Environment = "production"
+)
+
+// DefaultAuthPath is the OAuth token endpoint of every BNI gateway.
+const DefaultAuthPath = "/api/oauth/token"
+
+// Preset holds the defaults of a named Environment. BaseURL is empty when
+// there is no default and BNIServer must be set.
+type Preset struct {
+	BaseURL       string
+	AuthPath      string
+	MinTLSVersion uint16
+}
+
+var presets = map[Environment]Preset{
+	Sandbox: {
+		BaseURL:       "https://apidev.bni.co.id:8065",
+		AuthPath:      DefaultAuthPath,
+		MinTLSVersion: tls.VersionTLS12,
+	},
+	// Production has no BaseURL: BNI hands out the production endpoint when
+	// the account goes live, set it as BNIServer.
+	Production: {
+		AuthPath:      DefaultAuthPath,
+		MinTLSVersion: tls.VersionTLS12,
+	},
+}
+
+// Preset returns the defaults of e, ok is false for Custom and unknown environments.
+func (e Environment) Preset() (Preset, bool) {
+	preset, ok := presets[e]
+	return preset, ok
+}
+
+func (e Environment) valid() bool {
+	_, ok := presets[e]
+	return ok || e == "" || e == Custom
+}
+
+// ServerURL is BNIServer when set, otherwise the base URL of the Environment preset.
+func (c Config) ServerURL() string {
+	if c.BNIServer != "" {
+		return c.BNIServer
+	}
+	preset, _ := c.Environment.Preset()
+	return preset.BaseURL
+}
+
+// TokenPath is AuthPath when set, otherwise DefaultAuthPath.
+func (c Config) TokenPath() string {
+	if c.AuthPath != "" {
+		return c.AuthPath
+	}
+	if preset, ok := c.Environment.Preset(); ok {
+		return preset.AuthPath
+	}
+	return DefaultAuthPath
+}
+
+// TLSConfig returns the TLS settings of the Environment preset, nil for Custom.
+func (c Config) TLSConfig() *tls.Config {
+	preset, ok := c.Environment.Preset()
+	if !ok {
+		return nil
+	}
+	return &tls.Config{MinVersion: preset.MinTLSVersion}
+}
+
+// IsProduction reports whether the client talks to the production gateway.
+// There is no production host to recognize, so it must be declared with
+// Environment for the payment guard to apply.
+func (c Config) IsProduction() bool {
+	return c.Environment == Production
+}
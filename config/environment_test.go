@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Environment(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         Config
+		wantServerURL  string
+		wantProduction bool
+	}{
+		{name: "sandbox", config: Config{Environment: Sandbox},
+			wantServerURL: "https://apidev.bni.co.id:8065"},
+		{name: "production", config: Config{Environment: Production, BNIServer: "https://bni.example:8443"},
+			wantServerURL: "https://bni.example:8443", wantProduction: true},
+		{name: "server overrides preset", config: Config{Environment: Sandbox, BNIServer: "http://localhost:8065"},
+			wantServerURL: "http://localhost:8065"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantServerURL, tt.config.ServerURL())
+			assert.Equal(t, tt.wantProduction, tt.config.IsProduction())
+			assert.Equal(t, DefaultAuthPath, tt.config.TokenPath())
+		})
+	}
+}
+
+func TestConfig_Validate_environment(t *testing.T) {
+	config := Config{
+		Environment:     Sandbox,
+		Username:        "user",
+		Password:        "pass",
+		ClientID:        "IDBNI",
+		SignatureConfig: SignatureConfig{PrivateKeyPath: privateKeyPath},
+	}
+	assert.NoError(t, config.Validate())
+
+	config.Environment = "staging"
+	assert.Error(t, config.Validate())
+
+	config.Environment = Custom
+	assert.Error(t, config.Validate(), "custom environment requires bniServer")
+
+	config.Environment = Production
+	assert.Error(t, config.Validate(), "production environment requires bniServer")
+
+	config.BNIServer = "https://bni.example:8443"
+	assert.NoError(t, config.Validate())
+}
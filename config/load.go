@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+)
+
+// secretFileSuffix marks an env var holding the path of a file with the actual value,
+// e.g. BNI_PASSWORD_FILE=/run/secrets/bni_password.
+const secretFileSuffix = "_FILE"
+
+// Source fills a Config. Sources passed to Load are applied in order, each
+// overriding what earlier sources set: a file with every key it has, false
+// and empty values included, env vars only when not empty.
+type Source func(*Config) error
+
+// Load applies sources on top of an empty Config and validates the result.
+func Load(sources ...Source) (Config, error) {
+	var config Config
+	for _, source := range sources {
+		if err := source(&config); err != nil {
+			return Config{}, errors.Trace(err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// LoadFile loads and validates a YAML or JSON file, see FromFile.
+func LoadFile(path string) (Config, error) {
+	return Load(FromFile(path))
+}
+
+// LoadEnv loads and validates env vars, see FromEnv.
+func LoadEnv(prefix string) (Config, error) {
+	return Load(FromEnv(prefix))
+}
+
+// FromFile reads a YAML (.yaml, .yml) or JSON (.json) file.
+func FromFile(path string) Source {
+	return func(config *Config) error {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		// decoded onto the earlier sources, keys missing from the file keep their value
+		fileConfig := *config
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".yaml", ".yml":
+			err = yaml.UnmarshalStrict(content, &fileConfig)
+		case ".json":
+			decoder := json.NewDecoder(bytes.NewReader(content))
+			decoder.DisallowUnknownFields()
+			err = decoder.Decode(&fileConfig)
+		default:
+			return errors.NotSupportedf("config file extension %q", ext)
+		}
+		if err != nil {
+			return errors.Annotate(err, path)
+		}
+
+		*config = fileConfig
+		return nil
+	}
+}
+
+// FromEnv reads the env var named prefix + the `env` tag of each field, e.g.
+// BNI_CLIENT_ID for prefix "BNI_". When <NAME>_FILE is set instead, the value
+// is read from that file with surrounding whitespace trimmed. Like file
+// values, empty ones are ignored rather than overriding earlier sources.
+func FromEnv(prefix string) Source {
+	return func(config *Config) error {
+		return fromEnv(reflect.ValueOf(config).Elem(), prefix)
+	}
+}
+
+func fromEnv(v reflect.Value, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, fieldType := v.Field(i), v.Type().Field(i)
+		if fieldType.Anonymous && field.Kind() == reflect.Struct {
+			if err := fromEnv(field, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := fieldType.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		name = prefix + name
+
+		value := os.Getenv(name)
+		if value == "" {
+			secretPath := os.Getenv(name + secretFileSuffix)
+			if secretPath == "" {
+				continue
+			}
+			content, err := ioutil.ReadFile(secretPath)
+			if err != nil {
+				return errors.Annotate(err, name+secretFileSuffix)
+			}
+			value = strings.TrimSpace(string(content))
+		}
+		if value == "" {
+			continue
+		}
+
+		if err := setString(field, value); err != nil {
+			return errors.Annotate(err, name)
+		}
+	}
+	return nil
+}
+
+func setString(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		field.SetBool(b)
+	default:
+		return errors.NotSupportedf("field kind %s", field.Kind())
+	}
+	return nil
+}
+
+// ValidationError lists every problem found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate reports all missing or malformed fields at once as a *ValidationError.
+func (c Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for name, value := range map[string]string{
+		"username":       c.Username,
+		"password":       c.Password,
+		"clientId":       c.ClientID,
+		"privateKeyPath": c.PrivateKeyPath,
+	} {
+		if value == "" {
+			addf("%s is required", name)
+		}
+	}
+
+	if !c.Environment.valid() {
+		addf("environment %q is not one of %s, %s, %s", c.Environment, Sandbox, Production, Custom)
+	}
+
+	if c.BNIServer != "" {
+		u, err := url.Parse(c.BNIServer)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			addf("bniServer %q is not an http(s) URL", c.BNIServer)
+		}
+	} else if preset, _ := c.Environment.Preset(); preset.BaseURL == "" {
+		addf("bniServer is required without the sandbox environment")
+	}
+
+	if c.PrivateKeyPath != "" {
+		if _, err := os.Stat(c.PrivateKeyPath); err != nil {
+			addf("privateKeyPath: %v", err)
+		}
+	}
+
+	if c.LogLevel != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			addf("logLevel %q is not a valid level", c.LogLevel)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return &ValidationError{Problems: problems}
+}
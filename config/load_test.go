@@ -0,0 +1,113 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const privateKeyPath = "../testdata/id_rsa.pem"
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	util.AssertErrNil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bni-config")
+	util.AssertErrNil(t, err)
+	defer os.RemoveAll(dir)
+
+	yamlPath := writeFile(t, dir, "bni.yaml", `
+username: yaml-user
+password: yaml-pass
+clientId: IDBNIYAML
+bniServer: https://apidev.bni.co.id:8065
+privateKeyPath: `+privateKeyPath+`
+logLevel: debug
+`)
+	jsonPath := writeFile(t, dir, "bni.json", `{"clientId": "IDBNIJSON", "logPath": "bni.log"}`)
+	secretPath := writeFile(t, dir, "password", "file-pass\n")
+
+	t.Run("yaml", func(t *testing.T) {
+		config, err := LoadFile(yamlPath)
+		util.AssertErrNil(t, err)
+		assert.Equal(t, Config{
+			Username:        "yaml-user",
+			Password:        "yaml-pass",
+			ClientID:        "IDBNIYAML",
+			BNIServer:       "https://apidev.bni.co.id:8065",
+			LogLevel:        "debug",
+			SignatureConfig: SignatureConfig{PrivateKeyPath: privateKeyPath},
+		}, config)
+	})
+
+	t.Run("layered with env and secret file", func(t *testing.T) {
+		os.Setenv("TEST_BNI_USERNAME", "env-user")
+		os.Setenv("TEST_BNI_PASSWORD_FILE", secretPath)
+		os.Setenv("TEST_BNI_CLIENT_ID", "")
+		defer os.Unsetenv("TEST_BNI_USERNAME")
+		defer os.Unsetenv("TEST_BNI_PASSWORD_FILE")
+		defer os.Unsetenv("TEST_BNI_CLIENT_ID")
+
+		config, err := Load(FromFile(yamlPath), FromFile(jsonPath), FromEnv("TEST_BNI_"))
+		util.AssertErrNil(t, err)
+		assert.Equal(t, "env-user", config.Username)
+		assert.Equal(t, "file-pass", config.Password)
+		assert.Equal(t, "IDBNIJSON", config.ClientID, "set but empty does not override")
+		assert.Equal(t, "bni.log", config.LogPath)
+		assert.Equal(t, "debug", config.LogLevel)
+	})
+
+	t.Run("later file sets a bool back to false", func(t *testing.T) {
+		base := writeFile(t, dir, "base.yaml", "allowProductionPayments: true\nclientId: IDBNIBASE\n")
+		override := writeFile(t, dir, "override.json", `{"allowProductionPayments": false}`)
+
+		config, err := Load(FromFile(yamlPath), FromFile(base), FromFile(override))
+		util.AssertErrNil(t, err)
+		assert.False(t, config.AllowProductionPayments)
+		assert.Equal(t, "IDBNIBASE", config.ClientID, "keys missing from a file are kept")
+		assert.Equal(t, "yaml-user", config.Username)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		path := writeFile(t, dir, "typo.json", `{"clientID": "IDBNI"}`)
+
+		_, err := LoadFile(path)
+		util.AssertErrNotNil(t, err)
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := writeFile(t, dir, "bni.toml", "")
+
+		_, err := LoadFile(path)
+		assert.True(t, errors.IsNotSupported(errors.Cause(err)))
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	err := Config{
+		BNIServer: "apidev.bni.co.id:8065",
+		LogLevel:  "verbose",
+		SignatureConfig: SignatureConfig{
+			PrivateKeyPath: "does-not-exist.pem",
+		},
+	}.Validate()
+
+	validationErr, ok := err.(*ValidationError)
+	if assert.True(t, ok) {
+		assert.Len(t, validationErr.Problems, 6)
+		assert.Contains(t, validationErr.Problems, "username is required")
+		assert.Contains(t, validationErr.Problems, "password is required")
+		assert.Contains(t, validationErr.Problems, "clientId is required")
+		assert.Contains(t, validationErr.Problems, `bniServer "apidev.bni.co.id:8065" is not an http(s) URL`)
+		assert.Contains(t, validationErr.Problems, `logLevel "verbose" is not a valid level`)
+	}
+}
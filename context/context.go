@@ -6,6 +6,9 @@ const (
 	HTTPReqIDKey  = "httpReqID"
 	HTTPSessIDKey = "httpSessID"
 	BNISessIDKey  = "bniSessID"
+	OperationKey  = "bniOperation"
+	ActorKey      = "bniActor"
+	TenantIDKey   = "bniTenantID"
 )
 
 // https://blog.gopheracademy.com/advent-2016/context-logging/
@@ -24,3 +27,36 @@ func WithHTTPSessID(ctx context.Context, sessionId string) context.Context {
 func WithBNISessID(ctx context.Context, sessionId string) context.Context {
 	return context.WithValue(ctx, BNISessIDKey, sessionId)
 }
+
+// WithOperation returns a context which knows the BNI operation being performed
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, OperationKey, operation)
+}
+
+// Operation returns the BNI operation set by WithOperation, or an empty string
+func Operation(ctx context.Context) string {
+	operation, _ := ctx.Value(OperationKey).(string)
+	return operation
+}
+
+// WithActor returns a context which knows who initiated the operation, for the audit trail
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ActorKey, actor)
+}
+
+// Actor returns the actor set by WithActor, or an empty string
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(ActorKey).(string)
+	return actor
+}
+
+// WithTenantID returns a context which knows the tenant a Registry routes to
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, TenantIDKey, tenantID)
+}
+
+// TenantID returns the tenant set by WithTenantID, or an empty string
+func TenantID(ctx context.Context) string {
+	tenantID, _ := ctx.Value(TenantIDKey).(string)
+	return tenantID
+}
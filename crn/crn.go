@@ -0,0 +1,155 @@
+// Package crn generates customer reference numbers in the shape BNI's own
+// examples use: a date prefix followed by digits, e.g. 20170227000000000020.
+//
+// A CRN is <date><node><sequence>. The node ID keeps instances sharing a
+// counter store apart and the sequence comes from the store, so numbers stay
+// unique across restarts as long as the store is persistent.
+package crn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// MaxLength is the longest CRN BNI accepts.
+	MaxLength = 20
+
+	DefaultDateLayout  = "20060102"
+	DefaultNodeWidth   = 2
+	DefaultSeqWidth    = 10
+	defaultCounterName = "crn"
+)
+
+// ErrSequenceExhausted is returned once the sequence no longer fits its width for the current key.
+var ErrSequenceExhausted = errors.New("crn sequence exhausted")
+
+// wib is the time zone BNI works in, the date prefix rolls over at its midnight.
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// CounterStore hands out increasing sequence numbers per key. Next must be
+// atomic across every process sharing the store and start at 1 for a new key.
+type CounterStore interface {
+	Next(ctx context.Context, key string) (int64, error)
+}
+
+type Option func(*Generator)
+
+// WithDateLayout sets the Go time layout of the prefix, "" for no date prefix.
+// The layout must only produce digits.
+func WithDateLayout(layout string) Option {
+	return func(g *Generator) {
+		g.dateLayout = layout
+	}
+}
+
+// WithNode sets the ID of this instance, written on width digits.
+func WithNode(id, width int) Option {
+	return func(g *Generator) {
+		g.node = id
+		g.nodeWidth = width
+	}
+}
+
+func WithSequenceWidth(width int) Option {
+	return func(g *Generator) {
+		g.seqWidth = width
+	}
+}
+
+// WithLocation sets the time zone of the date prefix, WIB by default.
+func WithLocation(location *time.Location) Option {
+	return func(g *Generator) {
+		g.location = location
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(g *Generator) {
+		g.now = now
+	}
+}
+
+// WithCounterName namespaces the counter keys, for several generators on one store.
+func WithCounterName(name string) Option {
+	return func(g *Generator) {
+		g.counterName = name
+	}
+}
+
+// Generator creates CRNs, it is safe for concurrent use when its store is.
+type Generator struct {
+	store       CounterStore
+	dateLayout  string
+	node        int
+	nodeWidth   int
+	seqWidth    int
+	location    *time.Location
+	now         func() time.Time
+	counterName string
+}
+
+// New checks the format fits in MaxLength and the node ID fits its width.
+func New(store CounterStore, opts ...Option) (*Generator, error) {
+	g := &Generator{
+		store:       store,
+		dateLayout:  DefaultDateLayout,
+		nodeWidth:   DefaultNodeWidth,
+		seqWidth:    DefaultSeqWidth,
+		location:    wib,
+		now:         time.Now,
+		counterName: defaultCounterName,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.nodeWidth < 0 || g.seqWidth <= 0 {
+		return nil, errors.NotValidf("node width %d and sequence width %d", g.nodeWidth, g.seqWidth)
+	}
+	if g.node < 0 || int64(g.node) >= pow10(g.nodeWidth) {
+		return nil, errors.NotValidf("node %d on %d digits", g.node, g.nodeWidth)
+	}
+
+	date := time.Date(2006, 12, 31, 0, 0, 0, 0, time.UTC).Format(g.dateLayout)
+	if strings.Trim(date, "0123456789") != "" {
+		return nil, errors.NotValidf("date layout %q, it must produce digits only", g.dateLayout)
+	}
+	if length := len(date) + g.nodeWidth + g.seqWidth; length > MaxLength {
+		return nil, errors.NotValidf("format of %d digits, BNI accepts at most %d", length, MaxLength)
+	}
+
+	return g, nil
+}
+
+// Next returns a new CRN.
+func (g *Generator) Next(ctx context.Context) (string, error) {
+	prefix := g.now().In(g.location).Format(g.dateLayout)
+	if g.nodeWidth > 0 {
+		prefix += fmt.Sprintf("%0*d", g.nodeWidth, g.node)
+	}
+
+	seq, err := g.store.Next(ctx, g.counterName+":"+prefix)
+	if err != nil {
+		return "", errors.Annotate(err, "crn counter")
+	}
+	digits := strconv.FormatInt(seq, 10)
+	if seq <= 0 || len(digits) > g.seqWidth {
+		return "", errors.Annotatef(ErrSequenceExhausted, "prefix %s", prefix)
+	}
+
+	return prefix + strings.Repeat("0", g.seqWidth-len(digits)) + digits, nil
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
@@ -0,0 +1,110 @@
+package crn
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var fixedClock = func() time.Time { return time.Date(2017, 2, 26, 20, 0, 0, 0, time.UTC) }
+
+func TestGenerator_Next(t *testing.T) {
+	g, err := New(NewMemoryStore(), WithNode(0, 2), WithClock(fixedClock))
+	if !util.AssertErrNil(t, err) {
+		return
+	}
+
+	first, err := g.Next(context.Background())
+	util.AssertErrNil(t, err)
+	second, err := g.Next(context.Background())
+	util.AssertErrNil(t, err)
+
+	// 20:00 UTC is already the 27th in Jakarta
+	assert.Equal(t, "20170227000000000001", first)
+	assert.Equal(t, "20170227000000000002", second)
+	assert.Len(t, first, MaxLength)
+}
+
+func TestNew_Format(t *testing.T) {
+	_, err := New(NewMemoryStore(), WithSequenceWidth(11))
+	assert.True(t, errors.IsNotValid(err))
+
+	_, err = New(NewMemoryStore(), WithNode(100, 2))
+	assert.True(t, errors.IsNotValid(err))
+
+	_, err = New(NewMemoryStore(), WithDateLayout("2006-01-02"))
+	assert.True(t, errors.IsNotValid(err))
+
+	g, err := New(NewMemoryStore(), WithDateLayout(""), WithNode(7, 1), WithSequenceWidth(1))
+	if util.AssertErrNil(t, err) {
+		for i := 1; i <= 9; i++ {
+			_, err := g.Next(context.Background())
+			util.AssertErrNil(t, err)
+		}
+		_, err = g.Next(context.Background())
+		assert.Equal(t, ErrSequenceExhausted, errors.Cause(err))
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crn.db")
+	newGenerator := func(node int) (*Generator, *sql.DB) {
+		db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+		if !util.AssertErrNil(t, err) {
+			t.FailNow()
+		}
+		store := NewSQLStore(db, "")
+		util.AssertErrNil(t, store.CreateTable(context.Background()))
+
+		g, err := New(store, WithNode(node, 2), WithClock(fixedClock))
+		util.AssertErrNil(t, err)
+		return g, db
+	}
+
+	g, db := newGenerator(1)
+	crn, err := g.Next(context.Background())
+	util.AssertErrNil(t, err)
+	assert.Equal(t, "20170227010000000001", crn)
+	db.Close()
+
+	t.Run("survives a restart", func(t *testing.T) {
+		g, db := newGenerator(1)
+		defer db.Close()
+
+		crn, err := g.Next(context.Background())
+		util.AssertErrNil(t, err)
+		assert.Equal(t, "20170227010000000002", crn)
+	})
+
+	t.Run("unique under concurrency", func(t *testing.T) {
+		g, db := newGenerator(2)
+		defer db.Close()
+
+		var mutex sync.Mutex
+		seen := map[string]bool{}
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				crn, err := g.Next(context.Background())
+				util.AssertErrNil(t, err)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				assert.False(t, seen[crn], crn)
+				seen[crn] = true
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, seen, 20)
+	})
+}
@@ -0,0 +1,103 @@
+package crn
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// MemoryStore keeps counters in memory, only unique within one process lifetime.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	counters map[string]int64
+}
+
+var _ CounterStore = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]int64{}}
+}
+
+func (s *MemoryStore) Next(ctx context.Context, key string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[key]++
+	return s.counters[key], nil
+}
+
+// DefaultCounterTable is the table used by NewSQLStore when none is given.
+const DefaultCounterTable = "bni_crn_counter"
+
+// SQLStore keeps counters in a database/sql table, shared by every instance
+// using the same database. Its queries use "?" placeholders (SQLite, MySQL).
+type SQLStore struct {
+	db    *sql.DB
+	table string
+}
+
+var _ CounterStore = (*SQLStore)(nil)
+
+// NewSQLStore uses table, DefaultCounterTable when empty. The name is put in
+// the queries as is and must not come from user input.
+func NewSQLStore(db *sql.DB, table string) *SQLStore {
+	if table == "" {
+		table = DefaultCounterTable
+	}
+	return &SQLStore{db: db, table: table}
+}
+
+// CreateTable creates the counter table if it does not exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name VARCHAR(128) NOT NULL PRIMARY KEY,
+		value BIGINT NOT NULL
+	)`, s.table))
+	return errors.Trace(err)
+}
+
+// Next increments inside a transaction, so the row stays locked until the new
+// value is read back. A concurrent first use of a key makes one insert fail,
+// that caller retries with the update.
+func (s *SQLStore) Next(ctx context.Context, key string) (int64, error) {
+	for attempt := 0; ; attempt++ {
+		value, err := s.next(ctx, key)
+		if err == nil || attempt == 2 {
+			return value, err
+		}
+	}
+}
+
+func (s *SQLStore) next(ctx context.Context, key string) (value int64, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET value = value + 1 WHERE name = ?`, s.table), key)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if affected == 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (name, value) VALUES (?, 1)`, s.table), key); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT value FROM %s WHERE name = ?`, s.table), key).Scan(&value); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return value, errors.Trace(tx.Commit())
+}
@@ -0,0 +1,130 @@
+package dto
+
+import "strconv"
+
+// === requests ===
+
+func (r *GetBalanceRequest) LogFields() LogFields { return LogFields{} }
+
+func (r *GetInHouseInquiryRequest) LogFields() LogFields { return LogFields{} }
+
+func (r *DoPaymentRequest) LogFields() LogFields {
+	return LogFields{CRN: r.CustomerReferenceNumber}
+}
+
+func (r *GetPaymentStatusRequest) LogFields() LogFields {
+	return LogFields{CRN: r.CustomerReferenceNumber}
+}
+
+func (r *GetInterBankInquiryRequest) LogFields() LogFields {
+	return LogFields{CRN: r.CustomerReferenceNumber}
+}
+
+func (r *GetInterBankPaymentRequest) LogFields() LogFields {
+	return LogFields{CRN: r.CustomerReferenceNumber, BankReference: r.RetrievalReffNum}
+}
+
+// === responses ===
+
+// LogFields of whichever response BNI sent, plus the HTTP status and duration of the call.
+func (r *ApiResponse) LogFields() LogFields {
+	var fields LogFields
+	if resp := r.response(); resp != nil {
+		fields = resp.LogFields()
+	}
+	if r != nil {
+		fields.HTTPStatus = r.StatusCode
+		fields.Duration = r.Duration
+	}
+	return fields
+}
+
+func (r *GetBalanceResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	return r.CommonResponse.logFields(r.Parameters.CommonResponseParam)
+}
+
+func (r *GetInHouseInquiryResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	return r.CommonResponse.logFields(r.Parameters.CommonResponseParam)
+}
+
+func (r *DoPaymentResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	fields := r.CommonResponse.logFields(r.Parameters.CommonResponseParam)
+	fields.CRN = firstNonEmpty(r.Parameters.CustomerReference.String(), fields.CRN)
+	fields.BankReference = firstNonEmpty(intString(r.Parameters.BankReference), fields.BankReference)
+	return fields
+}
+
+func (r *GetPaymentStatusResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	fields := r.CommonResponse.logFields(r.Parameters.CommonResponseParam)
+	fields.CRN = firstNonEmpty(r.Parameters.CustomerReference.String(), fields.CRN)
+	fields.BankReference = firstNonEmpty(intString(r.Parameters.BankReference), fields.BankReference)
+	return fields
+}
+
+func (r *GetInterBankInquiryResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	fields := r.CommonResponse.logFields(r.Parameters.CommonResponseParam)
+	fields.BankReference = firstNonEmpty(r.Parameters.RetrievalReffNum.String(), fields.BankReference)
+	return fields
+}
+
+func (r *GetInterBankPaymentResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	fields := r.CommonResponse.logFields(r.Parameters.CommonResponseParam)
+	fields.BankReference = firstNonEmpty(r.Parameters.CustomerReffNum.String(), fields.BankReference)
+	return fields
+}
+
+func (r *BadRespResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	return r.CommonResponse.logFields(r.Parameters)
+}
+
+func (r *BadRespGeneralErrorResponse) LogFields() LogFields {
+	if r == nil {
+		return LogFields{}
+	}
+	return r.CommonResponse.logFields(r.Parameters)
+}
+
+func (c CommonResponse) logFields(param CommonResponseParam) LogFields {
+	return LogFields{
+		RC:            param.ResponseCode,
+		CRN:           c.CustomerReference,
+		BankReference: c.BankReference,
+	}
+}
+
+func intString(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -1,41 +1,83 @@
 package dto
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/redact"
+)
+
+const (
+	DirectionRequest  = "REQUEST"
+	DirectionResponse = "RESPONSE"
+)
 
 type LogMsg struct {
-	Operation string `json:"OPERATION,omitempty"`
-	From      string `json:"FROM,omitempty"`
-	To        string `json:"TO,omitempty"`
-	// RC        string `json:"RC,omitempty"`
-	// CRN    string `json:"CRN,omitempty"`
-	RawMsg string `json:"RAW_MSG,omitempty"`
+	Operation     string `json:"OPERATION,omitempty"`
+	Direction     string `json:"DIRECTION,omitempty"`
+	From          string `json:"FROM,omitempty"`
+	To            string `json:"TO,omitempty"`
+	RC            string `json:"RC,omitempty"`
+	CRN           string `json:"CRN,omitempty"`
+	BankReference string `json:"BANK_REF,omitempty"`
+	HTTPStatus    int    `json:"HTTP_STATUS,omitempty"`
+	DurationMs    int64  `json:"DURATION_MS,omitempty"`
+	RawMsg        string `json:"RAW_MSG,omitempty"`
 }
 
-func BuildLogRequest(operation string, dtoReq interface{}) LogMsg {
-	jsonMsg, err := json.Marshal(dtoReq)
-	rawMsg := string(jsonMsg)
-	if err != nil {
-		rawMsg = err.Error()
-	}
+// LogFields are the values a LogMsg is indexed on.
+type LogFields struct {
+	RC            string
+	CRN           string
+	BankReference string
+	HTTPStatus    int
+	Duration      time.Duration
+}
 
-	return LogMsg{
-		Operation: operation,
-		From:      "BNI", To: "API",
-		// RC: rc, CRN: crn,
-		RawMsg: rawMsg,
-	}
+// Loggable is implemented by every request and response so LogMsg can be
+// populated without knowing the concrete type.
+type Loggable interface {
+	LogFields() LogFields
 }
-func BuildLogResponse(operation string, dtoResp interface{}) LogMsg {
-	jsonMsg, err := json.Marshal(dtoResp)
+
+// BuildLogRequest describes dtoReq, RAW_MSG goes through redactor (nil keeps everything).
+func BuildLogRequest(operation string, dtoReq interface{}, redactor *redact.Redactor) LogMsg {
+	logMsg := buildLogMsg(operation, dtoReq, redactor)
+	logMsg.Direction = DirectionRequest
+	logMsg.From, logMsg.To = "BNI", "API"
+
+	return logMsg
+}
+
+// BuildLogResponse describes dtoResp, RAW_MSG goes through redactor (nil keeps everything).
+func BuildLogResponse(operation string, dtoResp interface{}, redactor *redact.Redactor) LogMsg {
+	logMsg := buildLogMsg(operation, dtoResp, redactor)
+	logMsg.Direction = DirectionResponse
+	logMsg.From, logMsg.To = "API", "BNI"
+
+	return logMsg
+}
+
+func buildLogMsg(operation string, dto interface{}, redactor *redact.Redactor) LogMsg {
+	jsonMsg, err := json.Marshal(redactor.Redact(dto))
 	rawMsg := string(jsonMsg)
 	if err != nil {
 		rawMsg = err.Error()
 	}
 
-	return LogMsg{
+	logMsg := LogMsg{
 		Operation: operation,
-		From:      "API", To: "BNI",
-		// RC: rc, CRN: crn,
-		RawMsg: rawMsg,
+		RawMsg:    rawMsg,
+	}
+
+	if loggable, ok := dto.(Loggable); ok {
+		fields := loggable.LogFields()
+		logMsg.RC = fields.RC
+		logMsg.CRN = fields.CRN
+		logMsg.BankReference = fields.BankReference
+		logMsg.HTTPStatus = fields.HTTPStatus
+		logMsg.DurationMs = fields.Duration.Milliseconds()
 	}
+
+	return logMsg
 }
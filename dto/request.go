@@ -2,34 +2,34 @@ package dto
 
 type CommonRequest struct {
 	ClientID  string `json:"clientId,omitempty"`
-	Signature string `json:"signature,omitempty"`
+	Signature string `json:"signature,omitempty" redact:"drop"`
 }
 
 type GetBalanceRequest struct {
 	CommonRequest
-	AccountNo string `json:"accountNo,omitempty"`
+	AccountNo string `json:"accountNo,omitempty" redact:"mask"`
 }
 
 type GetInHouseInquiryRequest struct {
 	CommonRequest
-	AccountNo string `json:"accountNo,omitempty"`
+	AccountNo string `json:"accountNo,omitempty" redact:"mask"`
 }
 
 type DoPaymentRequest struct {
 	CommonRequest
 	CustomerReferenceNumber string `json:"customerReferenceNumber,omitempty"`
 	PaymentMethod           string `json:"paymentMethod,omitempty"`
-	DebitAccountNo          string `json:"debitAccountNo,omitempty"`
-	CreditAccountNo         string `json:"creditAccountNo,omitempty"`
+	DebitAccountNo          string `json:"debitAccountNo,omitempty" redact:"mask"`
+	CreditAccountNo         string `json:"creditAccountNo,omitempty" redact:"mask"`
 	ValueDate               string `json:"valueDate,omitempty"`
 	ValueCurrency           string `json:"valueCurrency,omitempty"`
 	ValueAmount             string `json:"valueAmount,omitempty"`
 	Remark                  string `json:"remark,omitempty"`
-	BeneficiaryEmailAddress string `json:"beneficiaryEmailAddress,omitempty"`
+	BeneficiaryEmailAddress string `json:"beneficiaryEmailAddress,omitempty" redact:"hash"`
 	DestinationBankCode     string `json:"destinationBankCode,omitempty"`
-	BeneficiaryName         string `json:"beneficiaryName,omitempty"`
-	BeneficiaryAddress1     string `json:"beneficiaryAddress1,omitempty"`
-	BeneficiaryAddress2     string `json:"beneficiaryAddress2,omitempty"`
+	BeneficiaryName         string `json:"beneficiaryName,omitempty" redact:"hash"`
+	BeneficiaryAddress1     string `json:"beneficiaryAddress1,omitempty" redact:"drop"`
+	BeneficiaryAddress2     string `json:"beneficiaryAddress2,omitempty" redact:"drop"`
 	ChargingModelId         string `json:"chargingModelId,omitempty"`
 }
 
@@ -41,19 +41,19 @@ type GetPaymentStatusRequest struct {
 type GetInterBankInquiryRequest struct {
 	CommonRequest
 	CustomerReferenceNumber string `json:"customerReferenceNumber,omitempty"`
-	AccountNum              string `json:"accountNum,omitempty"`
+	AccountNum              string `json:"accountNum,omitempty" redact:"mask"`
 	DestinationBankCode     string `json:"destinationBankCode,omitempty"`
-	DestinationAccountNum   string `json:"destinationAccountNum,omitempty"`
+	DestinationAccountNum   string `json:"destinationAccountNum,omitempty" redact:"mask"`
 }
 
 type GetInterBankPaymentRequest struct {
 	CommonRequest
 	CustomerReferenceNumber string `json:"customerReferenceNumber,omitempty"`
 	Amount                  string `json:"amount,omitempty"`
-	DestinationAccountNum   string `json:"destinationAccountNum,omitempty"`
-	DestinationAccountName  string `json:"destinationAccountName,omitempty"`
+	DestinationAccountNum   string `json:"destinationAccountNum,omitempty" redact:"mask"`
+	DestinationAccountName  string `json:"destinationAccountName,omitempty" redact:"hash"`
 	DestinationBankCode     string `json:"destinationBankCode,omitempty"`
 	DestinationBankName     string `json:"destinationBankName,omitempty"`
-	AccountNum              string `json:"accountNum,omitempty"`
+	AccountNum              string `json:"accountNum,omitempty" redact:"mask"`
 	RetrievalReffNum        string `json:"retrievalReffNum,omitempty"`
 }
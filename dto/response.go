@@ -3,11 +3,12 @@ package dto
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 // === AUTH resp ===
 type GetTokenResponse struct {
-	AccessToken string `json:"access_token,omitempty"`
+	AccessToken string `json:"access_token,omitempty" redact:"drop"`
 	TokenType   string `json:"token_type,omitempty"`
 	ExpiredIn   int64  `json:"expired_in,omitempty"`
 	Scope       string `json:"scope,omitempty"`
@@ -27,6 +28,8 @@ type ApiResponse struct {
 	BadRespGeneralErrorResponse *BadRespGeneralErrorResponse `json:"General Error Response,omitempty"`
 
 	StatusCode int
+	// Duration is the time spent talking to BNI, retries included.
+	Duration time.Duration `json:"-"`
 }
 
 type GetBalanceResponse struct {
@@ -36,9 +39,9 @@ type GetBalanceResponse struct {
 
 type GetBalanceResponseParam struct {
 	CommonResponseParam
-	CustomerName    string `json:"customerName,omitempty"`
+	CustomerName    string `json:"customerName,omitempty" redact:"hash"`
 	AccountCurrency string `json:"accountCurrency,omitempty"`
-	AccountBalance  int64  `json:"accountBalance,omitempty"`
+	AccountBalance  int64  `json:"accountBalance,omitempty" redact:"drop"`
 }
 
 type GetInHouseInquiryResponse struct {
@@ -48,9 +51,9 @@ type GetInHouseInquiryResponse struct {
 
 type GetInHouseInquiryResponseParam struct {
 	CommonResponseParam
-	CustomerName    string `json:"customerName,omitempty"`
+	CustomerName    string `json:"customerName,omitempty" redact:"hash"`
 	AccountCurrency string `json:"accountCurrency,omitempty"`
-	AccountNumber   string `json:"accountNumber,omitempty"`
+	AccountNumber   string `json:"accountNumber,omitempty" redact:"mask"`
 	AccountStatus   string `json:"accountStatus,omitempty"`
 	AccountType     string `json:"accountType,omitempty"`
 }
@@ -62,8 +65,8 @@ type DoPaymentResponse struct {
 
 type DoPaymentResponseParam struct {
 	CommonResponseParam
-	DebitAccountNo    int64       `json:"debitAccountNo,omitempty"`
-	CreditAccountNo   int64       `json:"creditAccountNo,omitempty"`
+	DebitAccountNo    int64       `json:"debitAccountNo,omitempty" redact:"mask"`
+	CreditAccountNo   int64       `json:"creditAccountNo,omitempty" redact:"mask"`
 	ValueAmount       int64       `json:"valueAmount,omitempty"`
 	ValueCurrency     string      `json:"valueCurrency,omitempty"`
 	BankReference     int64       `json:"bankReference,omitempty"`
@@ -81,8 +84,8 @@ type GetPaymentStatusResponseParamPreviousResponse struct {
 	PreviousResponseMessage   string `json:"previousResponseMessage,omitempty"`
 	PreviousResponseTimestamp string `json:"previousResponseTimestamp,omitempty"`
 
-	DebitAccountNo  int64  `json:"debitAccountNo,omitempty"`
-	CreditAccountNo int64  `json:"creditAccountNo,omitempty"`
+	DebitAccountNo  int64  `json:"debitAccountNo,omitempty" redact:"mask"`
+	CreditAccountNo int64  `json:"creditAccountNo,omitempty" redact:"mask"`
 	ValueAmount     int64  `json:"valueAmount,omitempty"`
 	ValueCurrency   string `json:"valueCurrency,omitempty"`
 }
@@ -103,8 +106,8 @@ type GetInterBankInquiryResponse struct {
 
 type GetInterBankInquiryResponseParam struct {
 	CommonResponseParam
-	DestinationAccountNum  string      `json:"destinationAccountNum,omitempty"`
-	DestinationAccountName string      `json:"destinationAccountName,omitempty"`
+	DestinationAccountNum  string      `json:"destinationAccountNum,omitempty" redact:"mask"`
+	DestinationAccountName string      `json:"destinationAccountName,omitempty" redact:"hash"`
 	DestinationBankName    string      `json:"destinationBankName,omitempty"`
 	RetrievalReffNum       json.Number `json:"retrievalReffNum,omitempty"`
 }
@@ -116,11 +119,11 @@ type GetInterBankPaymentResponse struct {
 
 type GetInterBankPaymentResponseParam struct {
 	CommonResponseParam
-	DestinationAccountNum  json.Number `json:"destinationAccountNum,omitempty"`
-	DestinationAccountName string      `json:"destinationAccountName,omitempty"`
+	DestinationAccountNum  json.Number `json:"destinationAccountNum,omitempty" redact:"mask"`
+	DestinationAccountName string      `json:"destinationAccountName,omitempty" redact:"hash"`
 	DestinationBankName    string      `json:"destinationBankName,omitempty"`
 	CustomerReffNum        json.Number `json:"customerReffNum,omitempty"`
-	AccountName            string      `json:"accountName,omitempty"`
+	AccountName            string      `json:"accountName,omitempty" redact:"hash"`
 }
 
 // === BAD resp ===
@@ -175,3 +178,102 @@ func GetCommonResponse(parentResp ParentResponse, keyResp string) (*CommonRespon
 
 	return &resp, nil
 }
+
+// CommonResponder is implemented by every response wrapped in ApiResponse,
+// so the common parameters can be read without knowing the concrete type.
+// The methods are safe to call on a nil pointer.
+type CommonResponder interface {
+	CommonParam() CommonResponseParam
+}
+
+func (r *GetBalanceResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters.CommonResponseParam
+}
+
+func (r *GetInHouseInquiryResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters.CommonResponseParam
+}
+
+func (r *DoPaymentResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters.CommonResponseParam
+}
+
+func (r *GetPaymentStatusResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters.CommonResponseParam
+}
+
+func (r *GetInterBankInquiryResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters.CommonResponseParam
+}
+
+func (r *GetInterBankPaymentResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters.CommonResponseParam
+}
+
+func (r *BadRespResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters
+}
+
+func (r *BadRespGeneralErrorResponse) CommonParam() CommonResponseParam {
+	if r == nil {
+		return CommonResponseParam{}
+	}
+	return r.Parameters
+}
+
+// CommonParam returns the common parameters of whichever response BNI sent.
+func (r *ApiResponse) CommonParam() CommonResponseParam {
+	if resp := r.response(); resp != nil {
+		return resp.CommonParam()
+	}
+	return CommonResponseParam{}
+}
+
+// response returns whichever response BNI sent, nil if none was recognized.
+func (r *ApiResponse) response() interface {
+	CommonResponder
+	Loggable
+} {
+	switch {
+	case r == nil:
+		return nil
+	case r.GetBalanceResponse != nil:
+		return r.GetBalanceResponse
+	case r.GetInHouseInquiryResponse != nil:
+		return r.GetInHouseInquiryResponse
+	case r.DoPaymentResponse != nil:
+		return r.DoPaymentResponse
+	case r.GetPaymentStatusResponse != nil:
+		return r.GetPaymentStatusResponse
+	case r.GetInterBankInquiryResponse != nil:
+		return r.GetInterBankInquiryResponse
+	case r.GetInterBankPaymentResponse != nil:
+		return r.GetInterBankPaymentResponse
+	case r.BadRespResponse != nil:
+		return r.BadRespResponse
+	case r.BadRespGeneralErrorResponse != nil:
+		return r.BadRespGeneralErrorResponse
+	}
+	return nil
+}
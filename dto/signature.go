@@ -0,0 +1,51 @@
+package dto
+
+// Signable is implemented by every request; the request is signed over
+// SignatureData, the canonical string BNI recomputes on its side.
+type Signable interface {
+	SignatureData() string
+	SetSignature(signature string)
+}
+
+func (r *CommonRequest) SetSignature(signature string) {
+	r.Signature = signature
+}
+
+func (r *GetBalanceRequest) SignatureData() string {
+	return r.ClientID + r.AccountNo
+}
+
+func (r *GetInHouseInquiryRequest) SignatureData() string {
+	return r.ClientID + r.AccountNo
+}
+
+func (r *DoPaymentRequest) SignatureData() string {
+	return r.ClientID +
+		r.CustomerReferenceNumber +
+		r.PaymentMethod +
+		r.DebitAccountNo +
+		r.CreditAccountNo +
+		r.ValueAmount +
+		r.ValueCurrency
+}
+
+func (r *GetPaymentStatusRequest) SignatureData() string {
+	return r.ClientID +
+		r.CustomerReferenceNumber
+}
+
+func (r *GetInterBankInquiryRequest) SignatureData() string {
+	return r.ClientID +
+		r.DestinationBankCode +
+		r.DestinationAccountNum +
+		r.AccountNum
+}
+
+func (r *GetInterBankPaymentRequest) SignatureData() string {
+	return r.ClientID +
+		r.DestinationAccountNum +
+		r.DestinationBankCode +
+		r.AccountNum +
+		r.Amount +
+		r.RetrievalReffNum
+}
@@ -0,0 +1,140 @@
+package bni
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/redact"
+	"github.com/juju/errors"
+)
+
+// maxBodySnippet bounds how much of an unexpected response body is kept in an HTTPError.
+const maxBodySnippet = 512
+
+// HTTPError is returned when BNI answers with a non-2xx status or a body that is not JSON,
+// e.g. an empty 401 from the token endpoint or an HTML page from the WAF.
+type HTTPError struct {
+	StatusCode  int
+	Header      http.Header
+	BodySnippet string
+}
+
+func (e *HTTPError) Error() string {
+	if e.BodySnippet == "" {
+		return fmt.Sprintf("bni: unexpected HTTP response: %d %s (empty body)",
+			e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("bni: unexpected HTTP response: %d %s: %q",
+		e.StatusCode, http.StatusText(e.StatusCode), e.BodySnippet)
+}
+
+// ResponseError is returned when BNI answers with one of its error responses,
+// or with no response for the operation, and keeps the response code and
+// message BNI sent. Its errors.Cause is BadResponseError.
+type ResponseError struct {
+	ResponseCode    string
+	ResponseMessage string
+}
+
+func (e *ResponseError) Error() string {
+	if e.ResponseCode == "" && e.ResponseMessage == "" {
+		return BadResponseError.Error()
+	}
+	return fmt.Sprintf("%s: %s %s", BadResponseError, e.ResponseCode, e.ResponseMessage)
+}
+
+func (e *ResponseError) Cause() error {
+	return BadResponseError
+}
+
+func newResponseError(dtoResp *dto.ApiResponse) *ResponseError {
+	param := dtoResp.CommonParam()
+	return &ResponseError{ResponseCode: param.ResponseCode, ResponseMessage: param.ResponseMessage}
+}
+
+// AsResponseError finds the *ResponseError err was traced or annotated from.
+func AsResponseError(err error) (*ResponseError, bool) {
+	for err != nil {
+		if responseErr, ok := err.(*ResponseError); ok {
+			return responseErr, true
+		}
+		wrapped, ok := err.(*errors.Err)
+		if !ok {
+			return nil, false
+		}
+		err = wrapped.Underlying()
+	}
+	return nil, false
+}
+
+// HasResponseCode reports whether err is a *ResponseError with one of codes.
+func HasResponseCode(err error, codes ...string) bool {
+	responseErr, ok := AsResponseError(err)
+	if !ok {
+		return false
+	}
+	for _, code := range codes {
+		if responseErr.ResponseCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func newHTTPError(resp *http.Response, body []byte) *HTTPError {
+	return &HTTPError{
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header.Clone(),
+		BodySnippet: bodySnippet(body),
+	}
+}
+
+// checkJSONResponse returns an *HTTPError unless resp is a 2xx carrying a JSON body.
+// The snippet kept in the error goes through redactor since errors end up in logs.
+func checkJSONResponse(resp *http.Response, body []byte, redactor *redact.Redactor) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || !isJSONBody(resp.Header, body) {
+		return newHTTPError(resp, redactor.RedactJSON(body))
+	}
+	return nil
+}
+
+// isJSONBody trusts an explicit JSON content type, rejects other explicit types
+// (html, xml, ...) and sniffs the first byte for missing or generic text types.
+func isJSONBody(header http.Header, body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return false
+	}
+
+	if contentType := header.Get("content-type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err == nil {
+			switch {
+			case strings.HasSuffix(mediaType, "json"):
+				return true
+			case mediaType != "text/plain" && mediaType != "application/octet-stream":
+				return false
+			}
+		}
+	}
+
+	return trimmed[0] == '{' || trimmed[0] == '['
+}
+
+func bodySnippet(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) <= maxBodySnippet {
+		return string(body)
+	}
+
+	snippet := body[:maxBodySnippet]
+	for len(snippet) > 0 && !utf8.Valid(snippet) {
+		snippet = snippet[:len(snippet)-1]
+	}
+	return string(snippet) + "..."
+}
@@ -0,0 +1,229 @@
+// Package inquiry caches account inquiries and scores how well the name BNI
+// returns matches the beneficiary name we expect.
+//
+//	client := inquiry.NewCache(bniClient, inquiry.WithTTL(time.Hour))
+//	resp, err := client.GetInHouseInquiry(ctx, &dto.GetInHouseInquiryRequest{AccountNo: "115471119"})
+//	if inquiry.MatchInHouse("Budi Santoso", resp) < 0.8 { ... }
+package inquiry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+)
+
+// BNIBankCode is the bank code in-house accounts are cached under.
+const BNIBankCode = "009"
+
+const (
+	DefaultTTL         = time.Hour
+	DefaultNegativeTTL = 5 * time.Minute
+	DefaultMaxEntries  = 10000
+)
+
+type Option func(*Cache)
+
+// WithTTL sets how long an account found is cached, 0 to not cache them.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long an account not found is cached, 0 to not cache them.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithNotFound sets which inquiry errors mean the account does not exist.
+// By default none does and no error is cached.
+func WithNotFound(fn func(error) bool) Option {
+	return func(c *Cache) {
+		c.notFound = fn
+	}
+}
+
+// WithNotFoundCodes caches the inquiry errors carrying one of the response
+// codes BNI answers for an account that does not exist. Other error answers,
+// such as a busy system, transport errors and timeouts are not cached.
+func WithNotFoundCodes(codes ...string) Option {
+	return WithNotFound(func(err error) bool {
+		return bni.HasResponseCode(err, codes...)
+	})
+}
+
+// WithMaxEntries bounds the cache, entries closest to expiry go first.
+func WithMaxEntries(n int) Option {
+	return func(c *Cache) {
+		if n > 0 {
+			c.maxEntries = n
+		}
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+// Cache is a bni.Client answering GetInHouseInquiry and GetInterBankInquiry
+// from memory when the same bank code and account number were looked up
+// recently. Every other operation goes straight to the wrapped client.
+//
+// A cached interbank answer carries the RetrievalReffNum of the first
+// inquiry, use the wrapped client for the inquiry that precedes a payment.
+type Cache struct {
+	bni.Client
+
+	ttl         time.Duration
+	negativeTTL time.Duration
+	notFound    func(error) bool
+	maxEntries  int
+	now         func() time.Time
+
+	mutex   sync.Mutex
+	entries map[key]entry
+	hits    int
+	misses  int
+}
+
+var _ bni.Client = (*Cache)(nil)
+
+type key struct {
+	interBank bool
+	bankCode  string
+	account   string
+}
+
+type entry struct {
+	inHouse   *dto.GetInHouseInquiryResponse
+	interBank *dto.GetInterBankInquiryResponse
+	err       error
+	expires   time.Time
+}
+
+func NewCache(client bni.Client, opts ...Option) *Cache {
+	c := &Cache{
+		Client:      client,
+		ttl:         DefaultTTL,
+		negativeTTL: DefaultNegativeTTL,
+		notFound:    func(error) bool { return false },
+		maxEntries:  DefaultMaxEntries,
+		now:         time.Now,
+		entries:     map[key]entry{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Cache) GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error) {
+	k := key{false, BNIBankCode, dtoReq.AccountNo}
+	if cached, ok := c.get(k); ok {
+		if cached.err != nil {
+			return nil, cached.err
+		}
+		resp := *cached.inHouse
+		return &resp, nil
+	}
+
+	resp, err := c.Client.GetInHouseInquiry(ctx, dtoReq)
+	if err == nil {
+		stored := *resp
+		c.put(k, entry{inHouse: &stored}, c.ttl)
+	} else if c.notFound(err) {
+		c.put(k, entry{err: err}, c.negativeTTL)
+	}
+	return resp, err
+}
+
+func (c *Cache) GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error) {
+	k := key{true, dtoReq.DestinationBankCode, dtoReq.DestinationAccountNum}
+	if cached, ok := c.get(k); ok {
+		if cached.err != nil {
+			return nil, cached.err
+		}
+		resp := *cached.interBank
+		return &resp, nil
+	}
+
+	resp, err := c.Client.GetInterBankInquiry(ctx, dtoReq)
+	if err == nil {
+		stored := *resp
+		c.put(k, entry{interBank: &stored}, c.ttl)
+	} else if c.notFound(err) {
+		c.put(k, entry{err: err}, c.negativeTTL)
+	}
+	return resp, err
+}
+
+// Invalidate forgets an account, e.g. after a payment to it was rejected.
+func (c *Cache) Invalidate(bankCode, account string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, key{false, bankCode, account})
+	delete(c.entries, key{true, bankCode, account})
+}
+
+// Stats returns the number of inquiries answered from the cache and sent to BNI.
+func (c *Cache) Stats() (hits, misses int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *Cache) get(k key) (entry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached, ok := c.entries[k]
+	if ok && !c.now().Before(cached.expires) {
+		delete(c.entries, k)
+		ok = false
+	}
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return cached, ok
+}
+
+func (c *Cache) put(k key, e entry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	e.expires = now.Add(ttl)
+	c.entries[k] = e
+	if len(c.entries) <= c.maxEntries {
+		return
+	}
+
+	for k, cached := range c.entries {
+		if !now.Before(cached.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for len(c.entries) > c.maxEntries {
+		var oldest key
+		first := true
+		for k, cached := range c.entries {
+			if first || cached.expires.Before(c.entries[oldest].expires) {
+				oldest, first = k, false
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
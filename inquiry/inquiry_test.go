@@ -0,0 +1,145 @@
+package inquiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/bnifake"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	fake := bnifake.New()
+	fake.GetInHouseInquiryFunc = func(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error) {
+		switch dtoReq.AccountNo {
+		case "404":
+			return nil, errors.Trace(&bni.ResponseError{ResponseCode: "0103", ResponseMessage: "Account not found"})
+		case "busy":
+			return nil, &bni.ResponseError{ResponseCode: "0100", ResponseMessage: "System busy"}
+		case "500":
+			return nil, errors.New("i/o timeout")
+		}
+		assert.Less(t, Similarity("Budi Santoso", "B S"), Similarity("Budi Santoso", "B SANTOSO"), "initials only")
+	assert.Less(t, Similarity("Budi Santoso", "B SANTOSO"), Similarity("Budi Santoso", "BUDI SANTOSO"))
+
+	resp := &dto.GetInHouseInquiryResponse{}
+		resp.Parameters.CustomerName = "Bpk BUDI SANTOSO"
+		return resp, nil
+	}
+
+	now := time.Now()
+	cache := NewCache(fake, WithTTL(time.Hour), WithNegativeTTL(time.Minute), WithNotFoundCodes("0103"),
+		WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+	inquire := func(account string) (*dto.GetInHouseInquiryResponse, error) {
+		return cache.GetInHouseInquiry(ctx, &dto.GetInHouseInquiryRequest{AccountNo: account})
+	}
+	calls := func() int { return len(fake.CallsTo(bni.GetInHouseInquiryOperation)) }
+
+	first, err := inquire("115471119")
+	util.AssertErrNil(t, err)
+	first.Parameters.CustomerName = "changed by the caller"
+	second, err := inquire("115471119")
+	util.AssertErrNil(t, err)
+	assert.Equal(t, "Bpk BUDI SANTOSO", second.Parameters.CustomerName)
+	assert.Equal(t, 1, calls())
+
+	for i := 0; i < 2; i++ {
+		_, err = inquire("404")
+		assert.Equal(t, bni.BadResponseError, errors.Cause(err))
+	}
+	assert.Equal(t, 2, calls(), "not found is cached")
+
+	for i := 0; i < 2; i++ {
+		_, err = inquire("busy")
+		util.AssertErrNotNil(t, err)
+	}
+	assert.Equal(t, 4, calls(), "other error answers are not cached")
+
+	for i := 0; i < 2; i++ {
+		_, err = inquire("500")
+		util.AssertErrNotNil(t, err)
+	}
+	assert.Equal(t, 6, calls(), "transport errors are not cached")
+
+	now = now.Add(2 * time.Minute)
+	inquire("404")
+	inquire("115471119")
+	assert.Equal(t, 7, calls(), "negative entries expire first")
+
+	cache.Invalidate(BNIBankCode, "115471119")
+	inquire("115471119")
+	assert.Equal(t, 8, calls())
+
+	hits, misses := cache.Stats()
+	assert.Equal(t, 3, hits)
+	assert.Equal(t, 8, misses)
+}
+
+func TestCache_NoNotFoundCodes(t *testing.T) {
+	fake := bnifake.New()
+	fake.GetInHouseInquiryFunc = func(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error) {
+		return nil, &bni.ResponseError{ResponseCode: "0103", ResponseMessage: "Account not found"}
+	}
+
+	cache := NewCache(fake)
+	for i := 0; i < 2; i++ {
+		_, err := cache.GetInHouseInquiry(context.Background(), &dto.GetInHouseInquiryRequest{AccountNo: "404"})
+		assert.Equal(t, bni.BadResponseError, errors.Cause(err))
+	}
+	assert.Len(t, fake.CallsTo(bni.GetInHouseInquiryOperation), 2, "no error is cached by default")
+}
+
+func TestCache_InterBank(t *testing.T) {
+	fake := bnifake.New()
+	fake.GetInterBankInquiryFunc = func(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error) {
+		resp := &dto.GetInterBankInquiryResponse{}
+		resp.Parameters.DestinationAccountName = "SITI " + dtoReq.DestinationBankCode
+		return resp, nil
+	}
+
+	cache := NewCache(fake, WithMaxEntries(1))
+	ctx := context.Background()
+	for _, bank := range []string{"014", "014", "002", "014"} {
+		resp, err := cache.GetInterBankInquiry(ctx, &dto.GetInterBankInquiryRequest{DestinationBankCode: bank, DestinationAccountNum: "3333333333"})
+		util.AssertErrNil(t, err)
+		assert.Equal(t, "SITI "+bank, resp.Parameters.DestinationAccountName)
+	}
+	assert.Len(t, fake.CallsTo(bni.GetInterBankInquiryOperation), 3, "keyed by bank code, one entry kept")
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		expected, actual string
+		min, max         float64
+	}{
+		{"Budi Santoso", "BUDI SANTOSO", 1, 1},
+		{"Budi Santoso", "Bpk. Budi Santoso", 1, 1},
+		{"Budi Santoso", "SANTOSO BUDI", 1, 1},
+		{"Muhammad Rizky Pratama", "M RIZKY PRATAMA", 0.9, 0.99},
+		{"Budi Santoso", "B S", 0.5, 0.8},
+		{"Pratama Wijaya Kusumawardhani", "PRATAMA WIJAYA KUSUMA", 0.9, 0.95},
+		{"Budi Santoso Wijaya", "BUDI SANTOSO", 0.85, 0.95},
+		{"Budi Santoso", "BUDI SANTOSA", 0.85, 0.95},
+		{"PT Maju Jaya", "MAJU JAYA", 1, 1},
+		{"Budi Santoso", "SITI AMINAH", 0, 0.4},
+		{"Budi Santoso", "", 0, 0},
+	}
+	for _, test := range tests {
+		score := Similarity(test.expected, test.actual)
+		assert.True(t, score >= test.min && score <= test.max, "%q vs %q: %.2f", test.expected, test.actual, score)
+	}
+
+	assert.Less(t, Similarity("Budi Santoso", "B S"), Similarity("Budi Santoso", "B SANTOSO"), "initials only")
+	assert.Less(t, Similarity("Budi Santoso", "B SANTOSO"), Similarity("Budi Santoso", "BUDI SANTOSO"))
+
+	resp := &dto.GetInHouseInquiryResponse{}
+	resp.Parameters.CustomerName = "BUDI SANTOSO"
+	assert.Equal(t, 1.0, MatchInHouse("budi santoso", resp))
+	assert.Equal(t, 0.0, MatchInterBank("budi santoso", nil))
+}
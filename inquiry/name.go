@@ -0,0 +1,143 @@
+package inquiry
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+)
+
+// titles are dropped before comparing names, banks add or drop them freely.
+var titles = map[string]bool{
+	"BPK": true, "BAPAK": true, "IBU": true, "SDR": true, "SDRI": true,
+	"TN": true, "NY": true, "NN": true, "MR": true, "MRS": true, "MS": true,
+	"PT": true, "CV": true, "TBK": true, "UD": true,
+}
+
+// initialScore is the score of a single letter matching a word it is the
+// initial of, kept below truncatedScore so a name given as initials only never
+// scores like the full name.
+const initialScore = 0.8
+
+// truncatedScore is the least score of a name that may have been cut at a
+// fixed width. It stays below 1: a name missing its last words may as well
+// be somebody else's.
+const truncatedScore = 0.9
+
+// Similarity scores from 0 to 1 how likely actual, the name a bank returned,
+// is the account holder named expected. Case, punctuation, titles (BPK, IBU,
+// PT, ...), word order, initials and the truncation banks apply to long names
+// are taken into account; 1 is the same name.
+func Similarity(expected, actual string) float64 {
+	expectedWords, actualWords := words(expected), words(actual)
+	if len(expectedWords) == 0 || len(actualWords) == 0 {
+		return 0
+	}
+
+	joinedExpected, joinedActual := strings.Join(expectedWords, " "), strings.Join(actualWords, " ")
+	whole := ratio(joinedExpected, joinedActual)
+	byWord := (wordScore(expectedWords, actualWords) + wordScore(actualWords, expectedWords)) / 2
+	score := whole
+	if byWord > score {
+		score = byWord
+	}
+
+	// bank names are often cut at a fixed width: compare with what is left
+	if score < truncatedScore && len(joinedActual) >= 10 && strings.HasPrefix(joinedExpected, joinedActual) {
+		score = truncatedScore
+	}
+	return score
+}
+
+// MatchInHouse scores the CustomerName of an in-house inquiry against expected.
+func MatchInHouse(expected string, resp *dto.GetInHouseInquiryResponse) float64 {
+	if resp == nil {
+		return 0
+	}
+	return Similarity(expected, resp.Parameters.CustomerName)
+}
+
+// MatchInterBank scores the DestinationAccountName of an interbank inquiry against expected.
+func MatchInterBank(expected string, resp *dto.GetInterBankInquiryResponse) float64 {
+	if resp == nil {
+		return 0
+	}
+	return Similarity(expected, resp.Parameters.DestinationAccountName)
+}
+
+func words(name string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !titles[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// wordScore averages, weighted by length, how well each word of from is
+// found in to. A single letter scores initialScore against a word it is the
+// initial of.
+func wordScore(from, to []string) float64 {
+	var total, weight float64
+	for _, word := range from {
+		best := 0.0
+		for _, other := range to {
+			score := ratio(word, other)
+			if (len(word) == 1 || len(other) == 1) && word[0] == other[0] && score < initialScore {
+				score = initialScore
+			}
+			if score > best {
+				best = score
+			}
+		}
+		total += best * float64(len(word))
+		weight += float64(len(word))
+	}
+	return total / weight
+}
+
+// ratio is 1 minus the Levenshtein distance over the longer length.
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,235 @@
+package bni
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+var (
+	// ErrCRNConflict is returned when a CRN is reused for a different payment.
+	ErrCRNConflict = errors.New("customer reference number already used for a different payment")
+	// ErrCRNPending is returned for a replay of a payment whose first attempt has
+	// no known outcome yet, GetPaymentStatus tells whether it went through.
+	ErrCRNPending = errors.New("customer reference number has a payment in flight or with unknown outcome")
+	// ErrLedgerEntryNotFound is returned by Ledger.Get for an unknown CRN.
+	ErrLedgerEntryNotFound = errors.New("ledger entry not found")
+)
+
+type LedgerState string
+
+const (
+	// LedgerPending is a payment reserved and being sent.
+	LedgerPending LedgerState = "PENDING"
+	// LedgerSucceeded is a payment BNI accepted, Response holds its response.
+	LedgerSucceeded LedgerState = "SUCCEEDED"
+	// LedgerFailed is a payment BNI rejected, Response holds the error response.
+	LedgerFailed LedgerState = "FAILED"
+	// LedgerUnknown is a payment sent without a usable answer (timeout, 5xx, ...).
+	LedgerUnknown LedgerState = "UNKNOWN"
+)
+
+// LedgerEntry is what a Ledger knows about one CRN.
+type LedgerEntry struct {
+	CRN         string
+	Operation   string
+	PayloadHash string
+	State       LedgerState
+	Response    []byte
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Ledger records every CRN sent by DoPayment and GetInterBankPayment so a CRN
+// is never sent twice. Implementations must make Reserve atomic across every
+// process sharing the ledger.
+type Ledger interface {
+	// Reserve stores entry unless its CRN is known. It returns the stored entry
+	// and whether it was created by this call.
+	Reserve(ctx context.Context, entry LedgerEntry) (LedgerEntry, bool, error)
+	// Complete sets the final state and response of a reserved CRN, it is also
+	// how an unknown outcome is resolved after checking GetPaymentStatus.
+	Complete(ctx context.Context, crn string, state LedgerState, response []byte) error
+	// Release removes a pending reservation whose payment was never sent, so
+	// the CRN can be used again. Entries in any other state are kept.
+	Release(ctx context.Context, crn string) error
+	Get(ctx context.Context, crn string) (LedgerEntry, error)
+}
+
+type nopLedger struct{}
+
+func (nopLedger) Reserve(ctx context.Context, entry LedgerEntry) (LedgerEntry, bool, error) {
+	return entry, true, nil
+}
+
+func (nopLedger) Complete(ctx context.Context, crn string, state LedgerState, response []byte) error {
+	return nil
+}
+
+func (nopLedger) Release(ctx context.Context, crn string) error {
+	return nil
+}
+
+func (nopLedger) Get(ctx context.Context, crn string) (LedgerEntry, error) {
+	return LedgerEntry{}, ErrLedgerEntryNotFound
+}
+
+// reserveCRN claims crn for a payment. It returns nil when the payment must be
+// sent, or the stored entry of an exact replay of a finished payment.
+func (b *BNI) reserveCRN(ctx context.Context, crn, payloadHash string) (*LedgerEntry, error) {
+	if _, nop := b.ledger.(nopLedger); !nop && crn == "" {
+		return nil, errors.NotValidf("empty customer reference number with a ledger")
+	}
+
+	now := time.Now().UTC()
+	entry, created, err := b.ledger.Reserve(ctx, LedgerEntry{
+		CRN:         crn,
+		Operation:   bniCtx.Operation(ctx),
+		PayloadHash: payloadHash,
+		State:       LedgerPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "ledger")
+	}
+	if created {
+		return nil, nil
+	}
+
+	if entry.Operation != bniCtx.Operation(ctx) || entry.PayloadHash != payloadHash {
+		return nil, errors.Annotatef(ErrCRNConflict, "CRN %s", crn)
+	}
+	switch entry.State {
+	case LedgerSucceeded, LedgerFailed:
+		return &entry, nil
+	default:
+		return nil, errors.Annotatef(ErrCRNPending, "CRN %s is %s", crn, entry.State)
+	}
+}
+
+// completeCRN stores the outcome of a payment sent after reserveCRN. Only an
+// answer BNI wrapped in one of its error responses counts as a failure,
+// anything else without a response is unknown.
+func (b *BNI) completeCRN(ctx context.Context, crn string, dtoParamResp interface{}, dtoResp *dto.ApiResponse, err error) {
+	state, response := LedgerUnknown, interface{}(nil)
+	switch {
+	case err == nil:
+		state, response = LedgerSucceeded, dtoParamResp
+	case dtoResp != nil && (dtoResp.BadRespResponse != nil || dtoResp.BadRespGeneralErrorResponse != nil):
+		state, response = LedgerFailed, dtoResp
+	}
+
+	var raw []byte
+	if response != nil {
+		var marshalErr error
+		if raw, marshalErr = json.Marshal(response); marshalErr != nil {
+			b.log(ctx).Error(errors.Details(errors.Annotate(marshalErr, "ledger")))
+		}
+	}
+
+	writeCtx, cancel := ledgerWriteContext()
+	defer cancel()
+	if err := b.ledger.Complete(writeCtx, crn, state, raw); err != nil {
+		b.log(ctx).Error(errors.Details(errors.Annotate(err, "ledger")))
+	}
+}
+
+// releaseCRN gives back a CRN reserved by reserveCRN when the payment failed
+// before it was sent.
+func (b *BNI) releaseCRN(ctx context.Context, crn string) {
+	writeCtx, cancel := ledgerWriteContext()
+	defer cancel()
+	if err := b.ledger.Release(writeCtx, crn); err != nil {
+		b.log(ctx).Error(errors.Details(errors.Annotate(err, "ledger")))
+	}
+}
+
+// ledgerWriteTimeout bounds the ledger writes done after a payment attempt.
+const ledgerWriteTimeout = 10 * time.Second
+
+// ledgerWriteContext is the context of the ledger writes done after a payment
+// attempt, which must happen even when the caller's context timed out:
+// otherwise the CRN is left pending for good.
+func ledgerWriteContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), ledgerWriteTimeout)
+}
+
+// replay returns what the first attempt of a payment returned: the stored
+// response decoded into dtoParamResp, or the *ResponseError of BNI's answer.
+func (e *LedgerEntry) replay(dtoParamResp interface{}) error {
+	if e.State != LedgerSucceeded {
+		var dtoResp dto.ApiResponse
+		if len(e.Response) > 0 {
+			if err := json.Unmarshal(e.Response, &dtoResp); err != nil {
+				return errors.Annotate(err, "ledger replay")
+			}
+		}
+		return newResponseError(&dtoResp)
+	}
+	return errors.Annotate(json.Unmarshal(e.Response, dtoParamResp), "ledger replay")
+}
+
+// MemoryLedger is a Ledger for a single process, entries are lost on restart.
+type MemoryLedger struct {
+	mutex   sync.Mutex
+	entries map[string]LedgerEntry
+}
+
+var _ Ledger = (*MemoryLedger)(nil)
+
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{entries: map[string]LedgerEntry{}}
+}
+
+func (l *MemoryLedger) Reserve(ctx context.Context, entry LedgerEntry) (LedgerEntry, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if existing, ok := l.entries[entry.CRN]; ok {
+		return existing, false, nil
+	}
+	l.entries[entry.CRN] = entry
+	return entry, true, nil
+}
+
+func (l *MemoryLedger) Complete(ctx context.Context, crn string, state LedgerState, response []byte) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry, ok := l.entries[crn]
+	if !ok {
+		return errors.Annotate(ErrLedgerEntryNotFound, crn)
+	}
+	entry.State = state
+	entry.Response = append([]byte(nil), response...)
+	entry.UpdatedAt = time.Now().UTC()
+	l.entries[crn] = entry
+	return nil
+}
+
+func (l *MemoryLedger) Release(ctx context.Context, crn string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if entry, ok := l.entries[crn]; ok && entry.State == LedgerPending {
+		delete(l.entries, crn)
+	}
+	return nil
+}
+
+func (l *MemoryLedger) Get(ctx context.Context, crn string) (LedgerEntry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry, ok := l.entries[crn]
+	if !ok {
+		return LedgerEntry{}, errors.Annotate(ErrLedgerEntryNotFound, crn)
+	}
+	return entry, nil
+}
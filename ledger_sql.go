@@ -0,0 +1,126 @@
+package bni
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// DefaultLedgerTable is the table used by NewSQLLedger when none is given.
+const DefaultLedgerTable = "bni_ledger"
+
+// SQLLedger is a Ledger in a database/sql table, shared by every instance using
+// the same database. Its queries use "?" placeholders (SQLite, MySQL).
+type SQLLedger struct {
+	db    *sql.DB
+	table string
+}
+
+var _ Ledger = (*SQLLedger)(nil)
+
+// NewSQLLedger uses table, DefaultLedgerTable when empty. The name is put in
+// the queries as is and must not come from user input.
+func NewSQLLedger(db *sql.DB, table string) *SQLLedger {
+	if table == "" {
+		table = DefaultLedgerTable
+	}
+	return &SQLLedger{db: db, table: table}
+}
+
+// CreateTable creates the ledger table if it does not exist.
+func (l *SQLLedger) CreateTable(ctx context.Context) error {
+	_, err := l.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		crn VARCHAR(64) NOT NULL PRIMARY KEY,
+		operation VARCHAR(64) NOT NULL,
+		payload_hash VARCHAR(64) NOT NULL,
+		state VARCHAR(16) NOT NULL,
+		response TEXT,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL
+	)`, l.table))
+	return errors.Trace(err)
+}
+
+// Reserve relies on the primary key: when the insert fails the row is read
+// back, a missing row means the insert failed for another reason.
+func (l *SQLLedger) Reserve(ctx context.Context, entry LedgerEntry) (LedgerEntry, bool, error) {
+	_, insertErr := l.db.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (crn, operation, payload_hash, state, response, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, l.table),
+		entry.CRN, entry.Operation, entry.PayloadHash, string(entry.State),
+		nullableBytes(entry.Response), entry.CreatedAt.UnixNano(), entry.UpdatedAt.UnixNano(),
+	)
+	if insertErr == nil {
+		return entry, true, nil
+	}
+
+	existing, err := l.Get(ctx, entry.CRN)
+	if errors.Cause(err) == ErrLedgerEntryNotFound {
+		return LedgerEntry{}, false, errors.Trace(insertErr)
+	}
+	if err != nil {
+		return LedgerEntry{}, false, errors.Trace(err)
+	}
+	return existing, false, nil
+}
+
+func (l *SQLLedger) Complete(ctx context.Context, crn string, state LedgerState, response []byte) error {
+	result, err := l.db.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE %s SET state = ?, response = ?, updated_at = ? WHERE crn = ?`, l.table),
+		string(state), nullableBytes(response), time.Now().UnixNano(), crn,
+	)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if affected == 0 {
+		return errors.Annotate(ErrLedgerEntryNotFound, crn)
+	}
+	return nil
+}
+
+func (l *SQLLedger) Release(ctx context.Context, crn string) error {
+	_, err := l.db.ExecContext(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE crn = ? AND state = ?`, l.table), crn, string(LedgerPending))
+	return errors.Trace(err)
+}
+
+func (l *SQLLedger) Get(ctx context.Context, crn string) (LedgerEntry, error) {
+	var entry LedgerEntry
+	var state string
+	var response sql.NullString
+	var createdAt, updatedAt int64
+
+	err := l.db.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT crn, operation, payload_hash, state, response, created_at, updated_at
+		FROM %s WHERE crn = ?`, l.table), crn,
+	).Scan(&entry.CRN, &entry.Operation, &entry.PayloadHash, &state, &response, &createdAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return LedgerEntry{}, errors.Annotate(ErrLedgerEntryNotFound, crn)
+	}
+	if err != nil {
+		return LedgerEntry{}, errors.Trace(err)
+	}
+
+	entry.State = LedgerState(state)
+	if response.Valid {
+		entry.Response = []byte(response.String)
+	}
+	entry.CreatedAt = time.Unix(0, createdAt).UTC()
+	entry.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return entry, nil
+}
+
+func nullableBytes(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
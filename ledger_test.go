@@ -0,0 +1,203 @@
+package bni
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSQLLedger(t *testing.T) *SQLLedger {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "ledger.db"))
+	if !util.AssertErrNil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ledger := NewSQLLedger(db, "")
+	util.AssertErrNil(t, ledger.CreateTable(context.Background()))
+	return ledger
+}
+
+func TestLedger(t *testing.T) {
+	ledgers := map[string]func(t *testing.T) Ledger{
+		"memory": func(t *testing.T) Ledger { return NewMemoryLedger() },
+		"sql":    func(t *testing.T) Ledger { return newTestSQLLedger(t) },
+	}
+
+	for name, newLedger := range ledgers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			ledger := newLedger(t)
+
+			entry := LedgerEntry{CRN: "1", Operation: DoPaymentOperation, PayloadHash: "abc", State: LedgerPending}
+			stored, created, err := ledger.Reserve(ctx, entry)
+			util.AssertErrNil(t, err)
+			assert.True(t, created)
+			assert.Equal(t, "abc", stored.PayloadHash)
+
+			other := entry
+			other.PayloadHash = "def"
+			stored, created, err = ledger.Reserve(ctx, other)
+			util.AssertErrNil(t, err)
+			assert.False(t, created)
+			assert.Equal(t, "abc", stored.PayloadHash)
+
+			util.AssertErrNil(t, ledger.Complete(ctx, "1", LedgerSucceeded, []byte(`{"ok":true}`)))
+			stored, err = ledger.Get(ctx, "1")
+			if util.AssertErrNil(t, err) {
+				assert.Equal(t, LedgerSucceeded, stored.State)
+				assert.Equal(t, `{"ok":true}`, string(stored.Response))
+			}
+
+			_, err = ledger.Get(ctx, "2")
+			assert.Equal(t, ErrLedgerEntryNotFound, errors.Cause(err))
+			err = ledger.Complete(ctx, "2", LedgerFailed, nil)
+			assert.Equal(t, ErrLedgerEntryNotFound, errors.Cause(err))
+
+			util.AssertErrNil(t, ledger.Release(ctx, "1"))
+			_, err = ledger.Get(ctx, "1")
+			assert.NoError(t, err, "a completed entry is kept")
+
+			pending := entry
+			pending.CRN = "3"
+			_, _, err = ledger.Reserve(ctx, pending)
+			util.AssertErrNil(t, err)
+			util.AssertErrNil(t, ledger.Release(ctx, "3"))
+			_, created, err = ledger.Reserve(ctx, pending)
+			util.AssertErrNil(t, err)
+			assert.True(t, created, "a released CRN can be reserved again")
+		})
+	}
+}
+
+func TestBNI_DoPaymentWithLedger(t *testing.T) {
+	var hits int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write(getJSON("testdata/get_dopayment_response.json"))
+	}))
+	defer testServer.Close()
+
+	ledger := newTestSQLLedger(t)
+	bni := New(config.Config{
+		BNIServer:       testServer.URL,
+		SignatureConfig: dummySignatureConfig,
+	}, WithLedger(ledger))
+	bni.api.httpClient = testServer.Client()
+
+	newReq := func() *dto.DoPaymentRequest {
+		return &dto.DoPaymentRequest{
+			CustomerReferenceNumber: "20170227000000000020",
+			DebitAccountNo:          "113183203",
+			CreditAccountNo:         "115471119",
+			ValueAmount:             "100500",
+			ValueCurrency:           "IDR",
+		}
+	}
+
+	first, err := bni.DoPayment(context.Background(), newReq())
+	util.AssertErrNil(t, err)
+
+	t.Run("exact replay returns the stored response", func(t *testing.T) {
+		replayed, err := bni.DoPayment(context.Background(), newReq())
+		if util.AssertErrNil(t, err) {
+			assert.Equal(t, first, replayed)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("conflicting reuse is rejected", func(t *testing.T) {
+		req := newReq()
+		req.ValueAmount = "1"
+		_, err := bni.DoPayment(context.Background(), req)
+		assert.Equal(t, ErrCRNConflict, errors.Cause(err))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("unknown outcome blocks replays", func(t *testing.T) {
+		util.AssertErrNil(t, ledger.Complete(context.Background(), "20170227000000000020", LedgerUnknown, nil))
+		_, err := bni.DoPayment(context.Background(), newReq())
+		assert.Equal(t, ErrCRNPending, errors.Cause(err))
+	})
+}
+
+type failingAuditSink struct {
+	fail bool
+}
+
+func (s *failingAuditSink) Record(ctx context.Context, event AuditEvent) error {
+	if s.fail {
+		return errors.New("audit store down")
+	}
+	return nil
+}
+
+func TestBNI_DoPaymentAuditFailureReleasesCRN(t *testing.T) {
+	var hits int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write(getJSON("testdata/get_dopayment_response.json"))
+	}))
+	defer testServer.Close()
+
+	sink := &failingAuditSink{fail: true}
+	ledger := NewMemoryLedger()
+	bni := New(config.Config{
+		BNIServer:       testServer.URL,
+		SignatureConfig: dummySignatureConfig,
+	}, WithLedger(ledger), WithAuditSink(sink))
+	bni.api.httpClient = testServer.Client()
+
+	newReq := func() *dto.DoPaymentRequest {
+		return &dto.DoPaymentRequest{CustomerReferenceNumber: "20170227000000000020", ValueAmount: "100500"}
+	}
+
+	_, err := bni.DoPayment(context.Background(), newReq())
+	util.AssertErrNotNil(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+	_, err = ledger.Get(context.Background(), "20170227000000000020")
+	assert.Equal(t, ErrLedgerEntryNotFound, errors.Cause(err), "nothing sent, nothing kept")
+
+	sink.fail = false
+	_, err = bni.DoPayment(context.Background(), newReq())
+	util.AssertErrNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestBNI_DoPaymentCancelledStillCompletes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the caller gives up while BNI processes the payment
+		cancel()
+		w.WriteHeader(http.StatusOK)
+		w.Write(getJSON("testdata/get_dopayment_response.json"))
+	}))
+	defer testServer.Close()
+
+	ledger := newTestSQLLedger(t)
+	bni := New(config.Config{
+		BNIServer:       testServer.URL,
+		SignatureConfig: dummySignatureConfig,
+	}, WithLedger(ledger))
+	bni.api.httpClient = testServer.Client()
+
+	bni.DoPayment(ctx, &dto.DoPaymentRequest{CustomerReferenceNumber: "20170227000000000020", ValueAmount: "100500"})
+	stored, err := ledger.Get(context.Background(), "20170227000000000020")
+	if util.AssertErrNil(t, err) {
+		assert.NotEqual(t, LedgerPending, stored.State, "the outcome is recorded after the caller gave up")
+	}
+}
@@ -2,9 +2,11 @@ package logger
 
 import (
 	"context"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
 )
@@ -69,8 +71,43 @@ func init() {
 	logger = newLogger.Sugar()
 }
 
+var (
+	fileWritersMutex sync.Mutex
+	fileWriters      = map[string]*lumberjack.Logger{}
+)
+
+// NewFileLogger builds a JSON logger writing to a size-rotated file at path.
+// Loggers of the same path share one writer, so rotation happens once.
+func NewFileLogger(path string, level zapcore.Level) *zap.Logger {
+	fileWritersMutex.Lock()
+	writer, exist := fileWriters[path]
+	if !exist {
+		writer = &lumberjack.Logger{
+			Filename: path,
+			MaxSize:  500, // megabytes
+			// MaxBackups: 3,
+			// MaxAge:     28, // days
+		}
+		fileWriters[path] = writer
+	}
+	fileWritersMutex.Unlock()
+	fileWriteSyncer := zapcore.AddSync(writer)
+
+	return zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(DefaultEncoderConfig),
+		fileWriteSyncer,
+		level,
+	))
+}
+
+// Logger returns the package logger annotated with the IDs found in ctx.
 func Logger(ctx context.Context) *zap.SugaredLogger {
-	newLogger := logger
+	return WithContext(logger, ctx)
+}
+
+// WithContext returns l annotated with the IDs found in ctx.
+func WithContext(l *zap.SugaredLogger, ctx context.Context) *zap.SugaredLogger {
+	newLogger := l
 	if ctx != nil {
 		if ctxHTTPReqID, ok := ctx.Value(bniCtx.HTTPReqIDKey).(string); ok {
 			newLogger = newLogger.With(zap.String(bniCtx.HTTPReqIDKey, ctxHTTPReqID))
@@ -81,6 +118,9 @@ func Logger(ctx context.Context) *zap.SugaredLogger {
 		if ctxBNISessID, ok := ctx.Value(bniCtx.BNISessIDKey).(string); ok {
 			newLogger = newLogger.With(zap.String(bniCtx.BNISessIDKey, ctxBNISessID))
 		}
+		if ctxTenantID, ok := ctx.Value(bniCtx.TenantIDKey).(string); ok {
+			newLogger = newLogger.With(zap.String(bniCtx.TenantIDKey, ctxTenantID))
+		}
 	}
 	return newLogger
 }
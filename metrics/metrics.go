@@ -0,0 +1,46 @@
+package metrics
+
+import "time"
+
+// Response code categories reported by RCCategory.
+const (
+	RCSuccess = "success"
+	RCError   = "error"
+	RCNone    = "none"
+)
+
+// successResponseCode is what BNI sends when a request has been processed successfully.
+const successResponseCode = "0001"
+
+// Recorder receives measurements from a BNI client.
+// Implementations must be safe for concurrent use.
+type Recorder interface {
+	// ObserveOperation is called once per public BNI operation.
+	ObserveOperation(operation, rcCategory string, err error, duration time.Duration)
+	// ObserveHTTP is called once per HTTP round trip, statusCode is 0 on transport errors.
+	ObserveHTTP(operation string, statusCode int, duration time.Duration)
+	// IncRetry is called every time an operation is retried.
+	IncRetry(operation string)
+	// IncTokenRefresh is called after every attempt to obtain an access token.
+	IncTokenRefresh(err error)
+}
+
+// Nop is a Recorder that discards everything, used when no Recorder is configured.
+type Nop struct{}
+
+func (Nop) ObserveOperation(operation, rcCategory string, err error, duration time.Duration) {}
+func (Nop) ObserveHTTP(operation string, statusCode int, duration time.Duration)             {}
+func (Nop) IncRetry(operation string)                                                        {}
+func (Nop) IncTokenRefresh(err error)                                                        {}
+
+// RCCategory maps a BNI responseCode to a low cardinality label.
+func RCCategory(responseCode string) string {
+	switch responseCode {
+	case "":
+		return RCNone
+	case successResponseCode:
+		return RCSuccess
+	default:
+		return RCError
+	}
+}
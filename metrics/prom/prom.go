@@ -0,0 +1,114 @@
+// Package prom adapts metrics.Recorder to Prometheus.
+//
+//	collector := prom.New("myapp")
+//	prometheus.MustRegister(collector)
+//	client := bni.New(cfg, bni.WithMetrics(collector))
+package prom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is both a metrics.Recorder and a prometheus.Collector.
+type Collector struct {
+	operations        *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	retries           *prometheus.CounterVec
+	tokenRefreshes    *prometheus.CounterVec
+}
+
+var _ metrics.Recorder = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+// New creates a Collector whose metric names are prefixed with namespace (may be empty) and "bni".
+func New(namespace string) *Collector {
+	return &Collector{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bni",
+			Name: "operations_total",
+			Help: "BNI operations by operation, response code category and outcome.",
+		}, []string{"operation", "rc_category", "outcome"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "bni",
+			Name:    "operation_duration_seconds",
+			Help:    "BNI operation latency including signing, retries and token refresh.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bni",
+			Name: "http_requests_total",
+			Help: "HTTP round trips to BNI by operation and status code.",
+		}, []string{"operation", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "bni",
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of single HTTP round trips to BNI.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bni",
+			Name: "retries_total",
+			Help: "Retried BNI requests by operation.",
+		}, []string{"operation"}),
+		tokenRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bni",
+			Name: "token_refreshes_total",
+			Help: "Access token requests by outcome.",
+		}, []string{"outcome"}),
+	}
+}
+
+func (c *Collector) ObserveOperation(operation, rcCategory string, err error, duration time.Duration) {
+	c.operations.WithLabelValues(operation, rcCategory, outcome(err)).Inc()
+	c.operationDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+func (c *Collector) ObserveHTTP(operation string, statusCode int, duration time.Duration) {
+	status := "error"
+	if statusCode > 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	c.httpRequests.WithLabelValues(operation, status).Inc()
+	c.httpDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+func (c *Collector) IncRetry(operation string) {
+	c.retries.WithLabelValues(operation).Inc()
+}
+
+func (c *Collector) IncTokenRefresh(err error) {
+	c.tokenRefreshes.WithLabelValues(outcome(err)).Inc()
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.operations, c.operationDuration,
+		c.httpRequests, c.httpDuration,
+		c.retries, c.tokenRefreshes,
+	}
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,55 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/metrics"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	collector := New("myapp")
+	registry := prometheus.NewRegistry()
+	util.AssertErrNil(t, registry.Register(collector))
+
+	collector.ObserveOperation("DoPayment", metrics.RCSuccess, nil, 200*time.Millisecond)
+	collector.ObserveOperation("DoPayment", metrics.RCError, errors.New("bad response"), time.Second)
+	collector.ObserveHTTP("DoPayment", 200, 100*time.Millisecond)
+	collector.ObserveHTTP("DoPayment", 0, time.Second)
+	collector.IncRetry("GetBalance")
+	collector.IncTokenRefresh(nil)
+	collector.IncTokenRefresh(errors.New("unauthorized"))
+	collector.IncTokenRefresh(errors.New("unauthorized"))
+
+	expected := `
+# HELP myapp_bni_operations_total BNI operations by operation, response code category and outcome.
+# TYPE myapp_bni_operations_total counter
+myapp_bni_operations_total{operation="DoPayment",outcome="error",rc_category="error"} 1
+myapp_bni_operations_total{operation="DoPayment",outcome="ok",rc_category="success"} 1
+# HELP myapp_bni_http_requests_total HTTP round trips to BNI by operation and status code.
+# TYPE myapp_bni_http_requests_total counter
+myapp_bni_http_requests_total{operation="DoPayment",status="200"} 1
+myapp_bni_http_requests_total{operation="DoPayment",status="error"} 1
+# HELP myapp_bni_retries_total Retried BNI requests by operation.
+# TYPE myapp_bni_retries_total counter
+myapp_bni_retries_total{operation="GetBalance"} 1
+# HELP myapp_bni_token_refreshes_total Access token requests by outcome.
+# TYPE myapp_bni_token_refreshes_total counter
+myapp_bni_token_refreshes_total{outcome="error"} 2
+myapp_bni_token_refreshes_total{outcome="ok"} 1
+`
+	util.AssertErrNil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"myapp_bni_operations_total", "myapp_bni_http_requests_total",
+		"myapp_bni_retries_total", "myapp_bni_token_refreshes_total"))
+
+	count, err := testutil.GatherAndCount(registry, "myapp_bni_operation_duration_seconds", "myapp_bni_http_request_duration_seconds")
+	util.AssertErrNil(t, err)
+	assert.Equal(t, 2, count, "one histogram per operation")
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.tokenRefreshes.WithLabelValues("error")))
+}
@@ -0,0 +1,87 @@
+package bni
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/metrics"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedOperation struct {
+	operation, rcCategory string
+	err                   error
+}
+
+type recordingMetrics struct {
+	mutex          sync.Mutex
+	operations     []recordedOperation
+	httpStatuses   []int
+	retries        []string
+	tokenRefreshes int
+}
+
+func (r *recordingMetrics) ObserveOperation(operation, rcCategory string, err error, duration time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.operations = append(r.operations, recordedOperation{operation, rcCategory, err})
+}
+
+func (r *recordingMetrics) ObserveHTTP(operation string, statusCode int, duration time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.httpStatuses = append(r.httpStatuses, statusCode)
+}
+
+func (r *recordingMetrics) IncRetry(operation string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.retries = append(r.retries, operation)
+}
+
+func (r *recordingMetrics) IncTokenRefresh(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tokenRefreshes++
+}
+
+func TestBNI_WithMetrics(t *testing.T) {
+	var hits int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hits++
+		switch hits {
+		case 1:
+			w.WriteHeader(http.StatusUnauthorized)
+		case 2:
+			_, err := w.Write(getJSON("testdata/get_token_response.json"))
+			util.AssertErrNil(t, err)
+		default:
+			_, err := w.Write(getJSON("testdata/get_balance_response.json"))
+			util.AssertErrNil(t, err)
+		}
+	}))
+	defer testServer.Close()
+
+	recorder := &recordingMetrics{}
+	bni := New(config.Config{
+		BNIServer:       testServer.URL,
+		LogPath:         testLogPath,
+		SignatureConfig: dummySignatureConfig,
+	}, WithMetrics(recorder))
+	bni.api.httpClient = testServer.Client()
+
+	_, err := bni.GetBalance(context.Background(), &dto.GetBalanceRequest{AccountNo: "115471119"})
+	util.AssertErrNil(t, err)
+
+	assert.Equal(t, []recordedOperation{{GetBalanceOperation, metrics.RCSuccess, nil}}, recorder.operations)
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusOK, http.StatusOK}, recorder.httpStatuses)
+	assert.Equal(t, []string{GetBalanceOperation}, recorder.retries)
+	assert.Equal(t, 1, recorder.tokenRefreshes)
+}
@@ -16,3 +16,13 @@ const (
 	PaymentStatusRequest      = "PAYMENT_STATUS_REQUEST"
 	PaymentStatusResponse     = "PAYMENT_STATUS_RESPONSE"
 )
+
+const (
+	DoAuthOperation              = "DO_AUTH"
+	GetBalanceOperation          = "GET_BALANCE"
+	GetInHouseInquiryOperation   = "GET_IN_HOUSE_INQUIRY"
+	DoPaymentOperation           = "DO_PAYMENT"
+	GetPaymentStatusOperation    = "GET_PAYMENT_STATUS"
+	GetInterBankInquiryOperation = "GET_INTER_BANK_INQUIRY"
+	GetInterBankPaymentOperation = "GET_INTER_BANK_PAYMENT"
+)
@@ -0,0 +1,102 @@
+package bni
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/metrics"
+	"github.com/fundex-id/bni-api-mgmt/redact"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Option configures optional collaborators of a BNI instance.
+type Option func(*BNI)
+
+// WithMetrics reports every operation, HTTP round trip, retry and token refresh to r.
+func WithMetrics(r metrics.Recorder) Option {
+	return func(b *BNI) {
+		b.metrics = r
+	}
+}
+
+// WithTracerProvider traces every operation, with child spans for signing,
+// token acquisition and HTTP round trips, using a tracer from tp.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(b *BNI) {
+		b.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// WithLogger logs to l instead of the file configured by config.LogPath.
+func WithLogger(l *zap.Logger) Option {
+	return func(b *BNI) {
+		b.logger = l.Sugar()
+	}
+}
+
+// WithRedactionPolicies overrides the policies used to scrub logged payloads,
+// keyed by JSON field name, e.g. {"customerName": redact.Keep}.
+func WithRedactionPolicies(policies map[string]redact.Policy) Option {
+	return func(b *BNI) {
+		b.redactor = b.redactor.With(policies)
+	}
+}
+
+// WithAuditSink records the initiation, response and status resolution of
+// every DoPayment and GetInterBankPayment to sink.
+func WithAuditSink(sink AuditSink) Option {
+	return func(b *BNI) {
+		b.auditSink = sink
+	}
+}
+
+// WithHTTPClient sends every request, token included, through c instead of the
+// default pooled client, e.g. a cassette.Recorder or a client with a proxy.
+// TLS settings of the environment preset are not applied to c.
+func WithHTTPClient(c *http.Client) Option {
+	return func(b *BNI) {
+		b.api.httpClient = c
+	}
+}
+
+// WithLedger makes DoPayment and GetInterBankPayment reserve each CRN in l
+// before sending: an exact replay of a finished payment returns the stored
+// result, reusing a CRN for another payment fails with ErrCRNConflict.
+func WithLedger(l Ledger) Option {
+	return func(b *BNI) {
+		b.ledger = l
+	}
+}
+
+// CRNGenerator creates customer reference numbers, see package crn.
+type CRNGenerator interface {
+	Next(ctx context.Context) (string, error)
+}
+
+// WithCRNGenerator fills an empty CustomerReferenceNumber of DoPayment,
+// GetInterBankInquiry and GetInterBankPayment requests from g. The generated
+// CRN is left in the request for the caller to store.
+func WithCRNGenerator(g CRNGenerator) Option {
+	return func(b *BNI) {
+		b.crnGen = g
+	}
+}
+
+// RequestValidator checks interbank details before a request is signed and
+// sent, see package bank. It may fill fields it can derive, such as the bank
+// name from its code.
+type RequestValidator interface {
+	ValidateDoPayment(dtoReq *dto.DoPaymentRequest) error
+	ValidateInterBankInquiry(dtoReq *dto.GetInterBankInquiryRequest) error
+	ValidateInterBankPayment(dtoReq *dto.GetInterBankPaymentRequest) error
+}
+
+// WithRequestValidator runs v on DoPayment, GetInterBankInquiry and
+// GetInterBankPayment requests, a request it rejects is not sent.
+func WithRequestValidator(v RequestValidator) Option {
+	return func(b *BNI) {
+		b.validator = v
+	}
+}
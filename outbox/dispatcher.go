@@ -0,0 +1,308 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/status"
+	"github.com/juju/errors"
+)
+
+const (
+	DefaultBatchSize    = 10
+	DefaultLease        = 2 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 10
+)
+
+// ErrBadSignature is the Error of an intent failed for not matching its signature.
+var ErrBadSignature = errors.New("outbox intent signature mismatch")
+
+// notSentError is an attempt that failed before the payment went to BNI.
+type notSentError struct {
+	err error
+}
+
+func (e *notSentError) Error() string {
+	return e.err.Error()
+}
+
+type Option func(*Dispatcher)
+
+// WithBatchSize sets how many intents are claimed at once.
+func WithBatchSize(n int) Option {
+	return func(d *Dispatcher) {
+		if n > 0 {
+			d.batchSize = n
+		}
+	}
+}
+
+// WithLease sets how long a claimed intent is left to this dispatcher before
+// another may take it over. It must be longer than a BNI call with its retries.
+func WithLease(lease time.Duration) Option {
+	return func(d *Dispatcher) {
+		if lease > 0 {
+			d.lease = lease
+		}
+	}
+}
+
+// WithPollInterval sets the wait of Run between claims that found nothing.
+func WithPollInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		if interval > 0 {
+			d.pollInterval = interval
+		}
+	}
+}
+
+// WithBackoff sets the wait before checking an Unknown intent again, doubled
+// for each attempt up to max.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		if initial > 0 {
+			d.backoffInitial = initial
+		}
+		if max >= d.backoffInitial {
+			d.backoffMax = max
+		}
+	}
+}
+
+// WithMaxAttempts sets how many claims an intent gets before an unknown
+// outcome is left Abandoned, or one that never reached BNI is Failed.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		if n > 0 {
+			d.maxAttempts = n
+		}
+	}
+}
+
+// WithSigningKey makes the dispatcher fail intents whose Signature was not
+// made with key by Intent.Sign.
+func WithSigningKey(key []byte) Option {
+	return func(d *Dispatcher) {
+		d.signingKey = key
+	}
+}
+
+// WithPaymentNotFoundCodes sets the response codes BNI answers GetPaymentStatus
+// with for a CRN it never received. Only such an answer lets an intent whose
+// earlier attempt may have reached BNI be sent again; any other status error
+// leaves it Unknown. Without codes, such intents are never sent again and end
+// up Abandoned unless the status check settles them.
+func WithPaymentNotFoundCodes(codes ...string) Option {
+	return func(d *Dispatcher) {
+		d.notFoundCodes = codes
+	}
+}
+
+// WithResultHandler calls fn with each intent once its attempt is saved.
+func WithResultHandler(fn func(Intent)) Option {
+	return func(d *Dispatcher) {
+		d.onResult = fn
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(d *Dispatcher) {
+		d.now = now
+	}
+}
+
+// Dispatcher sends the intents of a Store. Several dispatchers may share a
+// store, each intent is sent by the one that claimed it.
+type Dispatcher struct {
+	client         bni.Client
+	store          Store
+	batchSize      int
+	lease          time.Duration
+	pollInterval   time.Duration
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	maxAttempts    int
+	signingKey     []byte
+	notFoundCodes  []string
+	onResult       func(Intent)
+	now            func() time.Time
+}
+
+func NewDispatcher(client bni.Client, store Store, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		client:         client,
+		store:          store,
+		batchSize:      DefaultBatchSize,
+		lease:          DefaultLease,
+		pollInterval:   DefaultPollInterval,
+		backoffInitial: 30 * time.Second,
+		backoffMax:     30 * time.Minute,
+		maxAttempts:    DefaultMaxAttempts,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run dispatches until ctx is done, which is the error it returns. Store
+// errors are retried after the poll interval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		n, err := d.RunOnce(ctx)
+		if n > 0 && err == nil {
+			// there may be more waiting
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.pollInterval):
+		}
+	}
+}
+
+// RunOnce claims one batch and dispatches it, returning how many intents it
+// handled.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	intents, err := d.store.Claim(ctx, d.now(), d.batchSize, d.lease)
+	if err != nil {
+		return 0, errors.Annotate(err, "claim")
+	}
+
+	for i, intent := range intents {
+		if ctx.Err() != nil {
+			// the rest are taken over when their lease expires
+			return i, errors.Trace(ctx.Err())
+		}
+
+		intent = d.dispatch(ctx, intent)
+		if err := d.store.Save(ctx, intent); err != nil {
+			return i, errors.Annotatef(err, "save %s", intent.ID)
+		}
+		if d.onResult != nil {
+			d.onResult(intent)
+		}
+	}
+	return len(intents), nil
+}
+
+// dispatch sends a claimed intent, after checking the status of one that
+// may have been sent already, and returns it with its new state.
+func (d *Dispatcher) dispatch(ctx context.Context, intent Intent) Intent {
+	if d.signingKey != nil && !intent.Verify(d.signingKey) {
+		return d.finish(intent, Failed, nil, ErrBadSignature)
+	}
+
+	if intent.ClaimedFrom == Unknown || intent.ClaimedFrom == Sending {
+		resp, err := d.client.GetPaymentStatus(ctx, &dto.GetPaymentStatusRequest{CustomerReferenceNumber: intent.CRN})
+		switch {
+		case err == nil && status.FromResponse(resp) == status.Success:
+			return d.finish(intent, Succeeded, resp, nil)
+		case err == nil && status.FromResponse(resp) == status.Failed:
+			return d.finish(intent, Failed, resp, errors.Errorf("payment failed at BNI: %s %s",
+				resp.Parameters.PreviousResponse.PreviousResponseCode, resp.Parameters.PreviousResponse.PreviousResponseMessage))
+		case err == nil:
+			// BNI has it but has not settled it
+			return d.finish(intent, Unknown, resp, errors.New("payment pending at BNI"))
+		case !bni.HasResponseCode(err, d.notFoundCodes...):
+			// busy, rate limited, ...: BNI may still have the payment
+			return d.finish(intent, Unknown, nil, errors.Annotate(err, "status"))
+		}
+		// BNI does not know the CRN, the payment never reached it
+	}
+
+	resp, err := d.send(ctx, intent)
+	if _, ok := errors.Cause(err).(*notSentError); ok {
+		// nothing reached BNI: no status to check before sending it again
+		return d.finish(intent, Pending, resp, err)
+	}
+	switch errors.Cause(err) {
+	case nil:
+		return d.finish(intent, Succeeded, resp, nil)
+	case bni.BadResponseError, bni.ErrCRNConflict, bni.ErrProductionPaymentsDisabled:
+		return d.finish(intent, Failed, resp, err)
+	}
+	if errors.IsNotValid(err) {
+		return d.finish(intent, Failed, resp, err)
+	}
+	return d.finish(intent, Unknown, resp, err)
+}
+
+func (d *Dispatcher) send(ctx context.Context, intent Intent) (interface{}, error) {
+	switch intent.Kind {
+	case KindInHouse:
+		req, err := intent.InHouse()
+		if err != nil {
+			return nil, errors.NewNotValid(err, "payload")
+		}
+		return d.client.DoPayment(ctx, req)
+
+	case KindInterBank:
+		req, err := intent.InterBank()
+		if err != nil {
+			return nil, errors.NewNotValid(err, "payload")
+		}
+		if req.RetrievalReffNum == "" {
+			inquiry, err := d.client.GetInterBankInquiry(ctx, &dto.GetInterBankInquiryRequest{
+				AccountNum:            req.AccountNum,
+				DestinationBankCode:   req.DestinationBankCode,
+				DestinationAccountNum: req.DestinationAccountNum,
+			})
+			if err != nil {
+				// nothing was sent, try again later
+				return nil, &notSentError{errors.Annotate(err, "inquiry")}
+			}
+			req.RetrievalReffNum = inquiry.Parameters.RetrievalReffNum.String()
+			if req.DestinationAccountName == "" {
+				req.DestinationAccountName = inquiry.Parameters.DestinationAccountName
+			}
+			if req.DestinationBankName == "" {
+				req.DestinationBankName = inquiry.Parameters.DestinationBankName
+			}
+		}
+		return d.client.GetInterBankPayment(ctx, req)
+	}
+	return nil, errors.NotValidf("intent kind %q", intent.Kind)
+}
+
+func (d *Dispatcher) finish(intent Intent, state State, resp interface{}, err error) Intent {
+	now := d.now().UTC()
+	intent.State = state
+	intent.UpdatedAt = now
+	intent.NextAttemptAt = now
+	intent.Error = ""
+	if err != nil {
+		intent.Error = err.Error()
+	}
+	if raw, marshalErr := json.Marshal(resp); resp != nil && marshalErr == nil && string(raw) != "null" {
+		intent.Response = raw
+	}
+
+	switch {
+	case state == Unknown && intent.Attempts >= d.maxAttempts:
+		intent.State = Abandoned
+	case state == Pending && intent.Attempts >= d.maxAttempts:
+		intent.State = Failed
+	case state == Unknown || state == Pending:
+		intent.NextAttemptAt = now.Add(d.backoff(intent.Attempts))
+	}
+	return intent
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.backoffInitial
+	for i := 1; i < attempts && wait < d.backoffMax; i++ {
+		wait *= 2
+	}
+	if wait > d.backoffMax {
+		wait = d.backoffMax
+	}
+	return wait
+}
@@ -0,0 +1,162 @@
+// Package outbox sends payments recorded in the caller's own database, so a
+// payout row and its payment are committed together:
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	// ... insert the payout row ...
+//	intent, _ := outbox.NewInHouse(payoutID, req)
+//	intent.Sign(key)
+//	store.Enqueue(ctx, tx, intent)
+//	tx.Commit()
+//
+// A Dispatcher, in the same process or another, claims committed intents,
+// calls BNI and records the outcome. An intent whose outcome is unknown, or
+// whose dispatcher died while sending it, is checked with GetPaymentStatus and
+// only sent again when BNI answers it does not know the CRN (see
+// WithPaymentNotFoundCodes), so a payment is never doubled.
+package outbox
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+// Kind is the BNI operation an intent is sent with.
+type Kind string
+
+const (
+	KindInHouse   Kind = "INHOUSE"
+	KindInterBank Kind = "INTERBANK"
+)
+
+type State string
+
+const (
+	// Pending is an intent waiting to be sent.
+	Pending State = "PENDING"
+	// Sending is an intent claimed by a dispatcher until NextAttemptAt.
+	Sending State = "SENDING"
+	// Succeeded is a payment BNI made, Response holds its response.
+	Succeeded State = "SUCCEEDED"
+	// Failed is a payment BNI did not make, Error tells why.
+	Failed State = "FAILED"
+	// Unknown is a payment sent without a usable answer, checked again at NextAttemptAt.
+	Unknown State = "UNKNOWN"
+	// Abandoned is an Unknown payment out of attempts, it needs checking by hand.
+	Abandoned State = "ABANDONED"
+)
+
+var (
+	ErrIntentNotFound  = errors.New("outbox intent not found")
+	ErrDuplicateIntent = errors.New("outbox intent already enqueued")
+	// ErrLeaseLost is returned by Store.Save when another dispatcher claimed the intent since.
+	ErrLeaseLost = errors.New("outbox intent claimed by another dispatcher")
+)
+
+// Intent is a payment to send. ID identifies it to the caller, e.g. the
+// payout row, and defaults to the CRN.
+type Intent struct {
+	ID        string
+	CRN       string
+	Kind      Kind
+	Payload   []byte
+	Signature string
+
+	State    State
+	Attempts int
+	Response []byte
+	Error    string
+
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	NextAttemptAt time.Time
+
+	// ClaimedFrom is the state the intent had when Store.Claim returned it.
+	ClaimedFrom State
+}
+
+// NewInHouse builds a Pending intent for DoPayment.
+func NewInHouse(id string, req *dto.DoPaymentRequest) (Intent, error) {
+	return newIntent(id, req.CustomerReferenceNumber, KindInHouse, req)
+}
+
+// NewInterBank builds a Pending intent for GetInterBankPayment. Without a
+// RetrievalReffNum the dispatcher does the inquiry first.
+func NewInterBank(id string, req *dto.GetInterBankPaymentRequest) (Intent, error) {
+	return newIntent(id, req.CustomerReferenceNumber, KindInterBank, req)
+}
+
+func newIntent(id, crn string, kind Kind, req interface{}) (Intent, error) {
+	// without a CRN an unknown outcome could not be checked
+	if crn == "" {
+		return Intent{}, errors.NotValidf("intent without customer reference number")
+	}
+	if id == "" {
+		id = crn
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return Intent{}, errors.Trace(err)
+	}
+
+	now := time.Now().UTC()
+	return Intent{
+		ID:            id,
+		CRN:           crn,
+		Kind:          kind,
+		Payload:       payload,
+		State:         Pending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// Sign sets Signature to an HMAC-SHA256 of what is sent, for a Dispatcher
+// built WithSigningKey to refuse intents altered in the database.
+func (i *Intent) Sign(key []byte) {
+	i.Signature = base64.StdEncoding.EncodeToString(i.mac(key))
+}
+
+// Verify tells whether Signature was made by Sign with key.
+func (i Intent) Verify(key []byte) bool {
+	signature, err := base64.StdEncoding.DecodeString(i.Signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(signature, i.mac(key))
+}
+
+func (i Intent) mac(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, part := range []string{i.ID, i.CRN, string(i.Kind)} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	mac.Write(i.Payload)
+	return mac.Sum(nil)
+}
+
+// InHouse decodes the payload of a KindInHouse intent.
+func (i Intent) InHouse() (*dto.DoPaymentRequest, error) {
+	if i.Kind != KindInHouse {
+		return nil, errors.NotValidf("%s intent as in-house payment", i.Kind)
+	}
+	var req dto.DoPaymentRequest
+	return &req, errors.Trace(json.Unmarshal(i.Payload, &req))
+}
+
+// InterBank decodes the payload of a KindInterBank intent.
+func (i Intent) InterBank() (*dto.GetInterBankPaymentRequest, error) {
+	if i.Kind != KindInterBank {
+		return nil, errors.NotValidf("%s intent as interbank payment", i.Kind)
+	}
+	var req dto.GetInterBankPaymentRequest
+	return &req, errors.Trace(json.Unmarshal(i.Payload, &req))
+}
@@ -0,0 +1,275 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/bnifake"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var signingKey = []byte("outbox-test-key")
+
+func newTestIntent(t *testing.T, crn string) Intent {
+	intent, err := NewInHouse("", &dto.DoPaymentRequest{
+		CustomerReferenceNumber: crn,
+		DebitAccountNo:          "113183203",
+		CreditAccountNo:         "115471119",
+		ValueAmount:             "100500",
+		ValueCurrency:           "IDR",
+	})
+	if !util.AssertErrNil(t, err) {
+		t.FailNow()
+	}
+	intent.Sign(signingKey)
+	return intent
+}
+
+func newTestSQLStore(t *testing.T) (*SQLStore, *sql.DB) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "outbox.db")+"?_busy_timeout=5000")
+	if !util.AssertErrNil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store := NewSQLStore(db, "")
+	util.AssertErrNil(t, store.CreateTable(context.Background()))
+	return store, db
+}
+
+// testStores enqueue through the store's own API and hand back the Store.
+var testStores = map[string]func(t *testing.T) (Store, func(Intent) error){
+	"memory": func(t *testing.T) (Store, func(Intent) error) {
+		store := NewMemoryStore()
+		return store, func(intent Intent) error { return store.Enqueue(context.Background(), intent) }
+	},
+	"sql": func(t *testing.T) (Store, func(Intent) error) {
+		store, db := newTestSQLStore(t)
+		return store, func(intent Intent) error { return store.Enqueue(context.Background(), db, intent) }
+	},
+}
+
+func TestStore(t *testing.T) {
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store, enqueue := newStore(t)
+
+			intent := newTestIntent(t, "20170227000000000020")
+			util.AssertErrNil(t, enqueue(intent))
+			assert.Equal(t, ErrDuplicateIntent, errors.Cause(enqueue(intent)))
+
+			now := time.Now()
+			claimed, err := store.Claim(ctx, now, 10, time.Minute)
+			util.AssertErrNil(t, err)
+			if !assert.Len(t, claimed, 1) {
+				return
+			}
+			assert.Equal(t, Pending, claimed[0].ClaimedFrom)
+			assert.Equal(t, Sending, claimed[0].State)
+			assert.Equal(t, 1, claimed[0].Attempts)
+			assert.True(t, claimed[0].Verify(signingKey))
+
+			again, err := store.Claim(ctx, now, 10, time.Minute)
+			util.AssertErrNil(t, err)
+			assert.Empty(t, again, "leased")
+
+			// the lease expired: another dispatcher takes it over
+			takenOver, err := store.Claim(ctx, now.Add(2*time.Minute), 10, time.Minute)
+			util.AssertErrNil(t, err)
+			if assert.Len(t, takenOver, 1) {
+				assert.Equal(t, Sending, takenOver[0].ClaimedFrom)
+			}
+
+			claimed[0].State = Succeeded
+			assert.Equal(t, ErrLeaseLost, errors.Cause(store.Save(ctx, claimed[0])))
+
+			takenOver[0].State = Succeeded
+			takenOver[0].Response = []byte(`{"ok":true}`)
+			util.AssertErrNil(t, store.Save(ctx, takenOver[0]))
+
+			stored, err := store.Get(ctx, intent.ID)
+			if util.AssertErrNil(t, err) {
+				assert.Equal(t, Succeeded, stored.State)
+				assert.Equal(t, `{"ok":true}`, string(stored.Response))
+			}
+
+			_, err = store.Get(ctx, "nope")
+			assert.Equal(t, ErrIntentNotFound, errors.Cause(err))
+		})
+	}
+}
+
+func TestSQLStore_EnqueueInTransaction(t *testing.T) {
+	ctx := context.Background()
+	store, db := newTestSQLStore(t)
+
+	tx, err := db.BeginTx(ctx, nil)
+	util.AssertErrNil(t, err)
+	util.AssertErrNil(t, store.Enqueue(ctx, tx, newTestIntent(t, "20170227000000000020")))
+	util.AssertErrNil(t, tx.Rollback())
+
+	_, err = store.Get(ctx, "20170227000000000020")
+	assert.Equal(t, ErrIntentNotFound, errors.Cause(err))
+
+	tx, err = db.BeginTx(ctx, nil)
+	util.AssertErrNil(t, err)
+	util.AssertErrNil(t, store.Enqueue(ctx, tx, newTestIntent(t, "20170227000000000020")))
+	err = store.Enqueue(ctx, tx, newTestIntent(t, "20170227000000000020"))
+	assert.Equal(t, ErrDuplicateIntent, errors.Cause(err), "seen inside the transaction")
+	util.AssertErrNil(t, tx.Commit())
+
+	intent, err := store.Get(ctx, "20170227000000000020")
+	util.AssertErrNil(t, err)
+	assert.Equal(t, Pending, intent.State)
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	store, db := newTestSQLStore(t)
+
+	for _, crn := range []string{"20170227000000000020", "20170227000000000021", "20170227000000000022"} {
+		util.AssertErrNil(t, store.Enqueue(ctx, db, newTestIntent(t, crn)))
+	}
+	tampered := newTestIntent(t, "20170227000000000023")
+	tampered.Payload = []byte(`{"customerReferenceNumber":"20170227000000000023","valueAmount":"999999999"}`)
+	util.AssertErrNil(t, store.Enqueue(ctx, db, tampered))
+
+	fake := bnifake.New()
+	fake.DoPaymentFunc = func(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error) {
+		switch dtoReq.CustomerReferenceNumber {
+		case "20170227000000000021":
+			return nil, bni.BadResponseError
+		case "20170227000000000022":
+			return nil, errors.New("read: connection reset by peer")
+		}
+		resp := &dto.DoPaymentResponse{}
+		resp.Parameters.ResponseCode = "0001"
+		return resp, nil
+	}
+
+	now := time.Now()
+	clock := func() time.Time { return now }
+	var results []Intent
+	dispatcher := NewDispatcher(fake, store, WithSigningKey(signingKey), WithClock(clock),
+		WithBackoff(time.Minute, time.Hour), WithMaxAttempts(3),
+		WithResultHandler(func(intent Intent) { results = append(results, intent) }))
+
+	n, err := dispatcher.RunOnce(ctx)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, 4, n)
+
+	states := map[string]State{}
+	for _, intent := range results {
+		states[intent.CRN] = intent.State
+	}
+	assert.Equal(t, map[string]State{
+		"20170227000000000020": Succeeded,
+		"20170227000000000021": Failed,
+		"20170227000000000022": Unknown,
+		"20170227000000000023": Failed,
+	}, states)
+	assert.Len(t, fake.CallsTo(bni.DoPaymentOperation), 3, "the tampered intent is not sent")
+
+	t.Run("unknown outcome is checked before resending", func(t *testing.T) {
+		n, err := dispatcher.RunOnce(ctx)
+		util.AssertErrNil(t, err)
+		assert.Equal(t, 0, n, "backing off")
+
+		status := &dto.GetPaymentStatusResponse{}
+		status.Parameters.PreviousResponse.TransactionStatus = "Y"
+		fake.QueueGetPaymentStatus(status, nil)
+
+		now = now.Add(time.Minute)
+		n, err = dispatcher.RunOnce(ctx)
+		util.AssertErrNil(t, err)
+		assert.Equal(t, 1, n)
+
+		intent, err := store.Get(ctx, "20170227000000000022")
+		util.AssertErrNil(t, err)
+		assert.Equal(t, Succeeded, intent.State)
+		assert.Equal(t, 2, intent.Attempts)
+		assert.Len(t, fake.CallsTo(bni.DoPaymentOperation), 3)
+	})
+
+	t.Run("a crashed dispatcher's intent is resent only when BNI never got it", func(t *testing.T) {
+		util.AssertErrNil(t, store.Enqueue(ctx, db, newTestIntent(t, "20170227000000000024")))
+		_, err := store.Claim(ctx, now, 10, time.Minute)
+		util.AssertErrNil(t, err)
+
+		fake.QueueGetPaymentStatus(nil, &bni.ResponseError{ResponseCode: "0100", ResponseMessage: "System busy"})
+		now = now.Add(2 * time.Minute)
+		n, err := dispatcher.RunOnce(ctx)
+		util.AssertErrNil(t, err)
+		assert.Equal(t, 1, n)
+
+		intent, err := store.Get(ctx, "20170227000000000024")
+		util.AssertErrNil(t, err)
+		assert.Equal(t, Unknown, intent.State, "any other error answer may hide a payment BNI has")
+		assert.Len(t, fake.CallsTo(bni.DoPaymentOperation), 3)
+
+		resender := NewDispatcher(fake, store, WithSigningKey(signingKey), WithClock(clock),
+			WithPaymentNotFoundCodes("0106"))
+		fake.QueueGetPaymentStatus(nil, errors.Trace(&bni.ResponseError{ResponseCode: "0106", ResponseMessage: "Payment not found"}))
+		now = now.Add(time.Hour)
+		n, err = resender.RunOnce(ctx)
+		util.AssertErrNil(t, err)
+		assert.Equal(t, 1, n)
+
+		intent, err = store.Get(ctx, "20170227000000000024")
+		util.AssertErrNil(t, err)
+		assert.Equal(t, Succeeded, intent.State)
+		assert.Len(t, fake.CallsTo(bni.DoPaymentOperation), 4)
+	})
+}
+
+func TestDispatcher_InquiryFailure(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	intent, err := NewInterBank("", &dto.GetInterBankPaymentRequest{
+		CustomerReferenceNumber: "20170227000000000030",
+		AccountNum:              "113183203",
+		DestinationBankCode:     "014",
+		DestinationAccountNum:   "3333333333",
+		Amount:                  "100500",
+	})
+	util.AssertErrNil(t, err)
+	util.AssertErrNil(t, store.Enqueue(ctx, intent))
+
+	inquiry := &dto.GetInterBankInquiryResponse{}
+	inquiry.Parameters.DestinationAccountName = "BUDI SANTOSO"
+	fake := bnifake.New().
+		QueueGetInterBankInquiry(nil, bni.BadResponseError).
+		QueueGetInterBankInquiry(inquiry, nil).
+		QueueGetInterBankPayment(&dto.GetInterBankPaymentResponse{}, nil)
+
+	now := time.Now()
+	dispatcher := NewDispatcher(fake, store, WithClock(func() time.Time { return now }),
+		WithBackoff(time.Minute, time.Hour))
+
+	_, err = dispatcher.RunOnce(ctx)
+	util.AssertErrNil(t, err)
+	stored, err := store.Get(ctx, intent.ID)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, Pending, stored.State, "nothing reached BNI")
+	assert.Equal(t, now.Add(time.Minute).UTC(), stored.NextAttemptAt)
+
+	now = now.Add(time.Minute)
+	_, err = dispatcher.RunOnce(ctx)
+	util.AssertErrNil(t, err)
+	stored, err = store.Get(ctx, intent.ID)
+	util.AssertErrNil(t, err)
+	assert.Equal(t, Succeeded, stored.State)
+	assert.Empty(t, fake.CallsTo(bni.GetPaymentStatusOperation), "no status to check")
+	if calls := fake.CallsTo(bni.GetInterBankPaymentOperation); assert.Len(t, calls, 1) {
+		assert.Equal(t, "BUDI SANTOSO", calls[0].Request.(*dto.GetInterBankPaymentRequest).DestinationAccountName)
+	}
+}
@@ -0,0 +1,215 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// DefaultTable is the table used by NewSQLStore when none is given.
+const DefaultTable = "bni_outbox"
+
+// Execer is what Enqueue writes with: a *sql.Tx of the caller, or a *sql.DB.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// SQLStore keeps intents in a database/sql table, shared by every dispatcher
+// using the same database. Its queries use "?" placeholders (SQLite, MySQL).
+type SQLStore struct {
+	db    *sql.DB
+	table string
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLStore uses table, DefaultTable when empty. The name is put in the
+// queries as is and must not come from user input.
+func NewSQLStore(db *sql.DB, table string) *SQLStore {
+	if table == "" {
+		table = DefaultTable
+	}
+	return &SQLStore{db: db, table: table}
+}
+
+// CreateTable creates the outbox table if it does not exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id VARCHAR(128) NOT NULL PRIMARY KEY,
+		crn VARCHAR(64) NOT NULL,
+		kind VARCHAR(16) NOT NULL,
+		payload TEXT NOT NULL,
+		signature VARCHAR(128) NOT NULL,
+		state VARCHAR(16) NOT NULL,
+		attempts INTEGER NOT NULL,
+		response TEXT,
+		error TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		next_attempt_at BIGINT NOT NULL
+	)`, s.table))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(
+		`CREATE INDEX IF NOT EXISTS %s_due ON %s (state, next_attempt_at)`, s.table, s.table))
+	return errors.Trace(err)
+}
+
+// Enqueue inserts intent with exec, pass the transaction writing the payout
+// row so both are committed or neither is. When the insert fails the ID is
+// looked up, through exec when it can query, and an existing row is reported
+// as ErrDuplicateIntent.
+func (s *SQLStore) Enqueue(ctx context.Context, exec Execer, intent Intent) error {
+	_, insertErr := exec.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (id, crn, kind, payload, signature, state, attempts, response, error, created_at, updated_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, s.table),
+		intent.ID, intent.CRN, string(intent.Kind), string(intent.Payload), intent.Signature,
+		string(intent.State), intent.Attempts, nullableBytes(intent.Response), intent.Error,
+		intent.CreatedAt.UnixNano(), intent.UpdatedAt.UnixNano(), intent.NextAttemptAt.UnixNano(),
+	)
+	if insertErr == nil {
+		return nil
+	}
+
+	var querier rowQuerier = s.db
+	if q, ok := exec.(rowQuerier); ok {
+		// sees the rows of the caller's transaction
+		querier = q
+	}
+	var id string
+	err := querier.QueryRowContext(ctx, fmt.Sprintf(`SELECT id FROM %s WHERE id = ?`, s.table), intent.ID).Scan(&id)
+	if err == nil {
+		return errors.Annotate(ErrDuplicateIntent, intent.ID)
+	}
+	return errors.Annotate(insertErr, intent.ID)
+}
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Claim reads candidates, then takes each with an update conditioned on the
+// state and attempts read, so two dispatchers never take the same intent.
+func (s *SQLStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Intent, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT %s FROM %s WHERE state IN (?, ?, ?) AND next_attempt_at <= ?
+		ORDER BY next_attempt_at LIMIT ?`, columns, s.table),
+		string(Pending), string(Unknown), string(Sending), now.UnixNano(), limit,
+	)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var candidates []Intent
+	for rows.Next() {
+		intent, err := scanIntent(rows)
+		if err != nil {
+			rows.Close()
+			return nil, errors.Trace(err)
+		}
+		candidates = append(candidates, intent)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var claimed []Intent
+	for _, intent := range candidates {
+		result, err := s.db.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE %s SET state = ?, attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+			WHERE id = ? AND state = ? AND attempts = ?`, s.table),
+			string(Sending), now.Add(lease).UnixNano(), now.UnixNano(),
+			intent.ID, string(intent.State), intent.Attempts,
+		)
+		if err != nil {
+			return claimed, errors.Trace(err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return claimed, errors.Trace(err)
+		}
+		if affected == 0 {
+			// taken by another dispatcher
+			continue
+		}
+
+		intent.ClaimedFrom = intent.State
+		intent.State = Sending
+		intent.Attempts++
+		intent.NextAttemptAt = now.Add(lease).UTC()
+		intent.UpdatedAt = now.UTC()
+		claimed = append(claimed, intent)
+	}
+	return claimed, nil
+}
+
+func (s *SQLStore) Save(ctx context.Context, intent Intent) error {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE %s SET state = ?, response = ?, error = ?, updated_at = ?, next_attempt_at = ?
+		WHERE id = ? AND state = ? AND attempts = ?`, s.table),
+		string(intent.State), nullableBytes(intent.Response), intent.Error,
+		intent.UpdatedAt.UnixNano(), intent.NextAttemptAt.UnixNano(),
+		intent.ID, string(Sending), intent.Attempts,
+	)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	if _, err := s.Get(ctx, intent.ID); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Annotate(ErrLeaseLost, intent.ID)
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (Intent, error) {
+	intent, err := scanIntent(s.db.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT %s FROM %s WHERE id = ?`, columns, s.table), id))
+	if errors.Cause(err) == sql.ErrNoRows {
+		return Intent{}, errors.Annotate(ErrIntentNotFound, id)
+	}
+	return intent, errors.Trace(err)
+}
+
+const columns = `id, crn, kind, payload, signature, state, attempts, response, error, created_at, updated_at, next_attempt_at`
+
+func scanIntent(row interface{ Scan(...interface{}) error }) (Intent, error) {
+	var intent Intent
+	var kind, payload, state string
+	var response sql.NullString
+	var createdAt, updatedAt, nextAttemptAt int64
+
+	err := row.Scan(&intent.ID, &intent.CRN, &kind, &payload, &intent.Signature, &state, &intent.Attempts,
+		&response, &intent.Error, &createdAt, &updatedAt, &nextAttemptAt)
+	if err != nil {
+		return Intent{}, errors.Trace(err)
+	}
+
+	intent.Kind = Kind(kind)
+	intent.Payload = []byte(payload)
+	intent.State = State(state)
+	if response.Valid {
+		intent.Response = []byte(response.String)
+	}
+	intent.CreatedAt = time.Unix(0, createdAt).UTC()
+	intent.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	intent.NextAttemptAt = time.Unix(0, nextAttemptAt).UTC()
+	return intent, nil
+}
+
+func nullableBytes(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
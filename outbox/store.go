@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Store is where intents wait. Enqueueing is not part of it, each
+// implementation takes what its transactions need, see SQLStore.Enqueue.
+type Store interface {
+	// Claim returns up to limit Pending, Unknown or Sending intents whose
+	// NextAttemptAt is not after now, oldest first, and marks them Sending
+	// until now+lease with one more attempt. An intent is claimed by one
+	// caller only, across every process sharing the store.
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Intent, error)
+	// Save stores the outcome of a claimed intent, unless it has been claimed
+	// again since, in which case it returns ErrLeaseLost.
+	Save(ctx context.Context, intent Intent) error
+	Get(ctx context.Context, id string) (Intent, error)
+}
+
+// claimable is the Claim condition shared by the implementations.
+func claimable(intent Intent, now time.Time) bool {
+	switch intent.State {
+	case Pending, Unknown, Sending:
+		return !intent.NextAttemptAt.After(now)
+	}
+	return false
+}
+
+// MemoryStore keeps intents in memory, for tests and single-process use
+// where losing the queue on restart is acceptable.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	intents map[string]Intent
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{intents: map[string]Intent{}}
+}
+
+func (s *MemoryStore) Enqueue(ctx context.Context, intent Intent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exist := s.intents[intent.ID]; exist {
+		return errors.Annotate(ErrDuplicateIntent, intent.ID)
+	}
+	s.intents[intent.ID] = intent
+	return nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Intent, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var claimed []Intent
+	for _, intent := range s.intents {
+		if claimable(intent, now) {
+			claimed = append(claimed, intent)
+		}
+	}
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].NextAttemptAt.Before(claimed[j].NextAttemptAt)
+	})
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	for i := range claimed {
+		intent := &claimed[i]
+		intent.ClaimedFrom = intent.State
+		intent.State = Sending
+		intent.Attempts++
+		intent.NextAttemptAt = now.Add(lease)
+		intent.UpdatedAt = now
+		s.intents[intent.ID] = *intent
+	}
+	return claimed, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, intent Intent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, exist := s.intents[intent.ID]
+	if !exist {
+		return errors.Annotate(ErrIntentNotFound, intent.ID)
+	}
+	if stored.State != Sending || stored.Attempts != intent.Attempts {
+		return errors.Annotate(ErrLeaseLost, intent.ID)
+	}
+	s.intents[intent.ID] = intent
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Intent, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	intent, exist := s.intents[id]
+	if !exist {
+		return Intent{}, errors.Annotate(ErrIntentNotFound, id)
+	}
+	return intent, nil
+}
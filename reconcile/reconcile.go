@@ -0,0 +1,312 @@
+// Package reconcile checks the payments we believe succeeded against the
+// debits BNI booked on the account, e.g. at the end of each day:
+//
+//	report := reconcile.New(reconcile.WithTimeWindow(24*time.Hour)).Run(payments, mutations)
+//	if !report.OK() { ... }
+//
+// A payment is first matched to a debit carrying its CRN or bank reference,
+// then, failing that, to a debit left over with the same amount on the same
+// account within the time window. A debit carrying the CRN or bank reference
+// of one of the payments is never matched by amount, it belongs to that one.
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Payment is one of our payment records.
+type Payment struct {
+	CRN           string
+	BankReference string
+	Account       string
+	// Amount is in the unit of the mutation records, e.g. whole rupiah.
+	Amount int64
+	Time   time.Time
+}
+
+// Direction of a mutation on the account.
+type Direction string
+
+const (
+	Debit  Direction = "D"
+	Credit Direction = "C"
+)
+
+// Mutation is one entry of the BNI account history.
+type Mutation struct {
+	Reference   string
+	Account     string
+	Amount      int64
+	Direction   Direction
+	Time        time.Time
+	Description string
+}
+
+// MatchKind tells how a payment was paired with a mutation.
+type MatchKind string
+
+const (
+	ByReference MatchKind = "REFERENCE"
+	ByAmount    MatchKind = "AMOUNT"
+)
+
+// Match pairs a payment with the mutation booking it. Problems lists why a
+// mismatched pair does not agree, it is empty for a match.
+type Match struct {
+	Payment  Payment
+	Mutation Mutation
+	Kind     MatchKind
+	Problems []string
+}
+
+// Report is the outcome of a reconciliation, each list in input order.
+type Report struct {
+	// Matched pairs agree within the tolerances.
+	Matched []Match
+	// Mismatched pairs share a reference but not the account or amount.
+	Mismatched []Match
+	// Missing payments have no debit on the account.
+	Missing []Payment
+	// Unexpected debits match none of our payments.
+	Unexpected []Mutation
+}
+
+// OK tells whether every payment matched and nothing else was debited.
+func (r *Report) OK() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0 && len(r.Unexpected) == 0
+}
+
+// DefaultTimeWindow is how far apart a payment and its debit may be for a
+// match by amount unless WithTimeWindow says otherwise.
+const DefaultTimeWindow = 24 * time.Hour
+
+type Option func(*Reconciler)
+
+// WithAmountTolerance accepts amounts differing by up to tolerance, e.g. for
+// a transfer fee booked together with the payment.
+func WithAmountTolerance(tolerance int64) Option {
+	return func(r *Reconciler) {
+		if tolerance >= 0 {
+			r.amountTolerance = tolerance
+		}
+	}
+}
+
+// WithTimeWindow sets how far apart in time a payment and its debit may be
+// for a match by amount, DefaultTimeWindow by default. A payment or debit
+// with a zero Time is never matched by amount.
+func WithTimeWindow(window time.Duration) Option {
+	return func(r *Reconciler) {
+		if window > 0 {
+			r.timeWindow = window
+		}
+	}
+}
+
+// WithoutAmountMatching only pairs payments and debits by reference.
+func WithoutAmountMatching() Option {
+	return func(r *Reconciler) {
+		r.amountMatching = false
+	}
+}
+
+// WithReferenceExtractor sets how the references of a mutation are read, by
+// default its Reference field plus the long numbers in its Description, where
+// BNI puts the CRN of host-to-host payments.
+func WithReferenceExtractor(fn func(Mutation) []string) Option {
+	return func(r *Reconciler) {
+		r.references = fn
+	}
+}
+
+// Reconciler matches payments to mutations, it holds no state between Runs.
+type Reconciler struct {
+	amountTolerance int64
+	timeWindow      time.Duration
+	amountMatching  bool
+	references      func(Mutation) []string
+}
+
+func New(opts ...Option) *Reconciler {
+	r := &Reconciler{
+		timeWindow:     DefaultTimeWindow,
+		amountMatching: true,
+		references:     DefaultReferences,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// DefaultReferences returns the Reference of m and the numbers of at least
+// six digits in its Description.
+func DefaultReferences(m Mutation) []string {
+	references := []string{m.Reference}
+	for _, word := range strings.FieldsFunc(m.Description, func(c rune) bool { return c < '0' || c > '9' }) {
+		if len(word) >= 6 {
+			references = append(references, word)
+		}
+	}
+	return references
+}
+
+// Run reconciles payments against mutations. Credits are ignored.
+func (r *Reconciler) Run(payments []Payment, mutations []Mutation) *Report {
+	report := &Report{}
+
+	var debits []int
+	for i, mutation := range mutations {
+		if mutation.Direction == Debit {
+			debits = append(debits, i)
+		}
+	}
+
+	paymentReferences := map[string]bool{}
+	for _, payment := range payments {
+		for _, reference := range []string{payment.CRN, payment.BankReference} {
+			if reference = normalize(reference); reference != "" {
+				paymentReferences[reference] = true
+			}
+		}
+	}
+
+	// index debits by every reference they carry; account numbers, dates and
+	// the like are references too, only the ones of a payment count
+	byReference := map[string][]int{}
+	referenced := map[int]bool{}
+	for _, i := range debits {
+		seen := map[string]bool{}
+		for _, reference := range r.references(mutations[i]) {
+			reference = normalize(reference)
+			if reference != "" && !seen[reference] {
+				seen[reference] = true
+				byReference[reference] = append(byReference[reference], i)
+				referenced[i] = referenced[i] || paymentReferences[reference]
+			}
+		}
+	}
+
+	used := map[int]bool{}
+	matches := make([]*Match, len(payments))
+	for p, payment := range payments {
+		if i, ok := r.findByReference(payment, mutations, byReference, used); ok {
+			used[i] = true
+			matches[p] = &Match{Payment: payment, Mutation: mutations[i], Kind: ByReference, Problems: r.problems(payment, mutations[i])}
+		}
+	}
+	if r.amountMatching {
+		for p, payment := range payments {
+			if matches[p] != nil {
+				continue
+			}
+			if i, ok := r.findByAmount(payment, mutations, debits, used, referenced); ok {
+				used[i] = true
+				matches[p] = &Match{Payment: payment, Mutation: mutations[i], Kind: ByAmount}
+			}
+		}
+	}
+
+	for p, match := range matches {
+		switch {
+		case match == nil:
+			report.Missing = append(report.Missing, payments[p])
+		case len(match.Problems) > 0:
+			report.Mismatched = append(report.Mismatched, *match)
+		default:
+			report.Matched = append(report.Matched, *match)
+		}
+	}
+	for _, i := range debits {
+		if !used[i] {
+			report.Unexpected = append(report.Unexpected, mutations[i])
+		}
+	}
+	return report
+}
+
+// findByReference returns the first unused debit carrying the CRN or bank
+// reference of payment, preferring one that agrees with it.
+func (r *Reconciler) findByReference(payment Payment, mutations []Mutation, byReference map[string][]int, used map[int]bool) (int, bool) {
+	var candidates []int
+	for _, reference := range []string{payment.CRN, payment.BankReference} {
+		for _, i := range byReference[normalize(reference)] {
+			if !used[i] {
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	for _, i := range candidates {
+		if len(r.problems(payment, mutations[i])) == 0 {
+			return i, true
+		}
+	}
+	return candidates[0], true
+}
+
+// findByAmount returns the unused debit carrying no payment's reference on
+// the payment account, within the tolerances, closest in time to the payment.
+func (r *Reconciler) findByAmount(payment Payment, mutations []Mutation, debits []int, used, referenced map[int]bool) (int, bool) {
+	if payment.Time.IsZero() {
+		return 0, false
+	}
+
+	best, found := 0, false
+	var bestGap time.Duration
+	for _, i := range debits {
+		mutation := mutations[i]
+		if used[i] || referenced[i] || mutation.Time.IsZero() ||
+			mutation.Account != payment.Account || !r.amountWithin(payment, mutation) {
+			continue
+		}
+		gap := absDuration(mutation.Time.Sub(payment.Time))
+		if gap > r.timeWindow {
+			continue
+		}
+		if !found || gap < bestGap {
+			best, bestGap, found = i, gap, true
+		}
+	}
+	return best, found
+}
+
+func (r *Reconciler) problems(payment Payment, mutation Mutation) []string {
+	var problems []string
+	if payment.Account != "" && mutation.Account != "" && payment.Account != mutation.Account {
+		problems = append(problems, fmt.Sprintf("account %s, expected %s", mutation.Account, payment.Account))
+	}
+	if !r.amountWithin(payment, mutation) {
+		problems = append(problems, fmt.Sprintf("amount %d, expected %d", mutation.Amount, payment.Amount))
+	}
+	return problems
+}
+
+func (r *Reconciler) amountWithin(payment Payment, mutation Mutation) bool {
+	diff := mutation.Amount - payment.Amount
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= r.amountTolerance
+}
+
+// normalize drops spaces and the leading zeros statements pad references with.
+func normalize(reference string) string {
+	reference = strings.TrimSpace(reference)
+	trimmed := strings.TrimLeft(reference, "0")
+	if trimmed == "" {
+		return reference
+	}
+	return trimmed
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,93 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var day = time.Date(2017, 2, 27, 0, 0, 0, 0, time.UTC)
+
+func TestReconciler_Run(t *testing.T) {
+	payments := []Payment{
+		{CRN: "20170227000000000020", Account: "113183203", Amount: 100500, Time: day.Add(8 * time.Hour)},
+		{CRN: "20170227000000000021", BankReference: "953403", Account: "113183203", Amount: 2000, Time: day.Add(9 * time.Hour)},
+		{CRN: "20170227000000000022", Account: "113183203", Amount: 300000, Time: day.Add(10 * time.Hour)},
+		{CRN: "20170227000000000023", Account: "113183203", Amount: 75000, Time: day.Add(11 * time.Hour)},
+		{CRN: "20170227000000000024", Account: "113183203", Amount: 1, Time: day.Add(12 * time.Hour)},
+	}
+	mutations := []Mutation{
+		{Description: "TRF H2H 20170227000000000020 BENEFICIARY", Account: "113183203", Amount: 100500, Direction: Debit, Time: day.Add(8 * time.Hour)},
+		{Reference: "000953403", Account: "113183203", Amount: 2006, Direction: Debit, Time: day.Add(9 * time.Hour)},
+		{Reference: "20170227000000000022", Account: "113183203", Amount: 310000, Direction: Debit, Time: day.Add(10 * time.Hour)},
+		{Account: "113183203", Amount: 75000, Direction: Debit, Time: day.Add(11*time.Hour + time.Minute)},
+		{Account: "113183203", Amount: 75000, Direction: Debit, Time: day.Add(23 * time.Hour)},
+		{Account: "113183203", Amount: 1000000, Direction: Credit, Time: day.Add(7 * time.Hour)},
+	}
+
+	report := New(WithAmountTolerance(10), WithTimeWindow(time.Hour)).Run(payments, mutations)
+	assert.False(t, report.OK())
+
+	if assert.Len(t, report.Matched, 3) {
+		assert.Equal(t, "20170227000000000020", report.Matched[0].Payment.CRN)
+		assert.Equal(t, ByReference, report.Matched[0].Kind)
+		assert.Equal(t, "20170227000000000021", report.Matched[1].Payment.CRN, "bank reference, within tolerance")
+		assert.Equal(t, ByAmount, report.Matched[2].Kind)
+		assert.Equal(t, day.Add(11*time.Hour+time.Minute), report.Matched[2].Mutation.Time, "closest in time")
+	}
+	if assert.Len(t, report.Mismatched, 1) {
+		assert.Equal(t, "20170227000000000022", report.Mismatched[0].Payment.CRN)
+		assert.Equal(t, []string{"amount 310000, expected 300000"}, report.Mismatched[0].Problems)
+	}
+	if assert.Len(t, report.Missing, 1) {
+		assert.Equal(t, "20170227000000000024", report.Missing[0].CRN)
+	}
+	if assert.Len(t, report.Unexpected, 1) {
+		assert.Equal(t, day.Add(23*time.Hour), report.Unexpected[0].Time)
+	}
+}
+
+func TestReconciler_WithoutAmountMatching(t *testing.T) {
+	payments := []Payment{{CRN: "1", Account: "113183203", Amount: 100, Time: day}}
+	mutations := []Mutation{{Account: "113183203", Amount: 100, Direction: Debit, Time: day.Add(time.Hour)}}
+
+	report := New(WithoutAmountMatching()).Run(payments, mutations)
+	assert.Len(t, report.Missing, 1)
+	assert.Len(t, report.Unexpected, 1)
+
+	report = New().Run(payments, mutations)
+	assert.True(t, report.OK())
+}
+
+func TestReconciler_AmountMatchingBounds(t *testing.T) {
+	payment := Payment{CRN: "20170227000000000020", Account: "113183203", Amount: 100, Time: day}
+	tests := map[string]Mutation{
+		"outside the default window": {Account: "113183203", Amount: 100, Direction: Debit, Time: day.Add(3 * 24 * time.Hour)},
+		"without time":               {Account: "113183203", Amount: 100, Direction: Debit},
+	}
+	for name, mutation := range tests {
+		report := New().Run([]Payment{payment}, []Mutation{mutation})
+		assert.Len(t, report.Missing, 1, name)
+		assert.Len(t, report.Unexpected, 1, name)
+	}
+
+	other := Payment{CRN: "20170227000000000021", Account: "113183203", Amount: 100, Time: day}
+	booked := Mutation{Description: "TRF H2H 20170227000000000020", Account: "113183203", Amount: 100, Direction: Debit, Time: day}
+	report := New().Run([]Payment{payment, other}, []Mutation{booked, booked})
+	assert.Len(t, report.Matched, 1)
+	assert.Equal(t, []Payment{other}, report.Missing, "a debit carrying another payment's CRN is not matched by amount")
+	assert.Len(t, report.Unexpected, 1)
+}
+
+func TestReconciler_AmountMatchingNumbersInDescription(t *testing.T) {
+	payment := Payment{CRN: "20170227000000000020", Account: "113183203", Amount: 100500, Time: day}
+	mutation := Mutation{Reference: "953403", Description: "TRF KE 115471119 BUDI 20170227", Account: "113183203",
+		Amount: 100500, Direction: Debit, Time: day.Add(time.Hour)}
+
+	report := New().Run([]Payment{payment}, []Mutation{mutation})
+	assert.True(t, report.OK())
+	if assert.Len(t, report.Matched, 1) {
+		assert.Equal(t, ByAmount, report.Matched[0].Kind, "an account number is not a payment reference")
+	}
+}
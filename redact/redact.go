@@ -0,0 +1,214 @@
+// Package redact removes sensitive values from payloads before they are logged.
+//
+// Policies are keyed by JSON field name and can be read from `redact` struct tags:
+//
+//	type Request struct {
+//		AccountNo string `json:"accountNo" redact:"mask"`
+//	}
+package redact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Policy tells a Redactor what to do with the value of a field.
+type Policy string
+
+const (
+	// Keep logs the value as is, useful to override a default policy.
+	Keep Policy = "keep"
+	// Mask keeps only the last MaskSuffix characters.
+	Mask Policy = "mask"
+	// Drop replaces the value with Redacted.
+	Drop Policy = "drop"
+	// Hash replaces the value with a short SHA-256 fingerprint, so equal values can still be correlated.
+	Hash Policy = "hash"
+)
+
+const (
+	// MaskSuffix is how many trailing characters Mask leaves visible.
+	MaskSuffix = 4
+	// Redacted is what Drop leaves in place of a value.
+	Redacted = "[REDACTED]"
+
+	hashPrefix = "sha256:"
+	hashLength = 16
+	tagName    = "redact"
+)
+
+// Redactor applies field policies to JSON documents. The zero value keeps everything.
+type Redactor struct {
+	policies map[string]Policy
+}
+
+// New returns a Redactor for the given policies, keyed by JSON field name.
+func New(policies map[string]Policy) *Redactor {
+	r := Redactor{policies: map[string]Policy{}}
+	for field, policy := range policies {
+		r.policies[field] = policy
+	}
+	return &r
+}
+
+// With returns a copy of r where policies override the existing ones.
+func (r *Redactor) With(policies map[string]Policy) *Redactor {
+	if r == nil {
+		return New(policies)
+	}
+
+	merged := New(r.policies)
+	for field, policy := range policies {
+		merged.policies[field] = policy
+	}
+	return merged
+}
+
+// Redact returns a JSON-marshalable copy of v with the policies applied.
+// If v can't be marshaled the error message is returned instead.
+// A nil Redactor returns v unchanged.
+func (r *Redactor) Redact(v interface{}) interface{} {
+	if r == nil {
+		return v
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+
+	tree, err := decode(raw)
+	if err != nil {
+		return err.Error()
+	}
+
+	return r.walk(tree)
+}
+
+// RedactJSON applies the policies to a raw JSON document.
+// Anything that is not JSON (an HTML error page, an empty body) is returned unchanged.
+func (r *Redactor) RedactJSON(raw []byte) []byte {
+	if r == nil {
+		return raw
+	}
+
+	tree, err := decode(raw)
+	if err != nil {
+		return raw
+	}
+
+	redacted, err := json.Marshal(r.walk(tree))
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
+
+func (r *Redactor) walk(node interface{}) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for field, child := range value {
+			if policy, ok := r.policies[field]; ok && policy != Keep {
+				value[field] = apply(policy, child)
+				continue
+			}
+			value[field] = r.walk(child)
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = r.walk(child)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func apply(policy Policy, value interface{}) interface{} {
+	var scalar string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		scalar = v
+	case json.Number:
+		scalar = v.String()
+	default:
+		return Redacted
+	}
+
+	switch policy {
+	case Mask:
+		return MaskString(scalar)
+	case Hash:
+		return HashString(scalar)
+	default:
+		return Redacted
+	}
+}
+
+// MaskString replaces all but the last MaskSuffix characters of s with '*'.
+func MaskString(s string) string {
+	if len(s) <= MaskSuffix {
+		return s
+	}
+	return strings.Repeat("*", len(s)-MaskSuffix) + s[len(s)-MaskSuffix:]
+}
+
+// HashString returns a short, stable fingerprint of s.
+func HashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hashPrefix + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// FromStructTags collects the `redact` tag of every field of the given structs,
+// keyed by the field's JSON name. Embedded and nested structs are included.
+func FromStructTags(values ...interface{}) map[string]Policy {
+	policies := map[string]Policy{}
+	for _, v := range values {
+		collectTags(reflect.TypeOf(v), policies, map[reflect.Type]bool{})
+	}
+	return policies
+}
+
+func collectTags(t reflect.Type, policies map[string]Policy, seen map[reflect.Type]bool) {
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if policy, ok := field.Tag.Lookup(tagName); ok {
+			policies[jsonName(field)] = Policy(policy)
+		}
+		collectTags(field.Type, policies, seen)
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func decode(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
@@ -0,0 +1,76 @@
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type embedded struct {
+	Signature string `json:"signature,omitempty" redact:"drop"`
+}
+
+type payload struct {
+	embedded
+	AccountNo string `json:"accountNo" redact:"mask"`
+	Name      string `json:"name" redact:"hash"`
+	Balance   int64  `json:"balance" redact:"drop"`
+	Currency  string `json:"currency"`
+	Nested    *struct {
+		AccountNo int64 `json:"accountNo"`
+	} `json:"nested,omitempty"`
+}
+
+func TestFromStructTags(t *testing.T) {
+	assert.Equal(t, map[string]Policy{
+		"signature": Drop,
+		"accountNo": Mask,
+		"name":      Hash,
+		"balance":   Drop,
+	}, FromStructTags(payload{}))
+}
+
+func TestRedactor_Redact(t *testing.T) {
+	redactor := New(FromStructTags(payload{}))
+
+	given := payload{
+		embedded:  embedded{Signature: "c2lnbmF0dXJl"},
+		AccountNo: "115471119",
+		Name:      "Mr.X",
+		Balance:   16732765949981,
+		Currency:  "IDR",
+	}
+	given.Nested = &struct {
+		AccountNo int64 `json:"accountNo"`
+	}{AccountNo: 113183203}
+
+	got, err := json.Marshal(redactor.Redact(given))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"signature": "[REDACTED]",
+		"accountNo": "*****1119",
+		"name": "`+HashString("Mr.X")+`",
+		"balance": "[REDACTED]",
+		"currency": "IDR",
+		"nested": {"accountNo": "*****3203"}
+	}`, string(got))
+
+	assert.Equal(t, "IDR", given.Currency, "original value must not be modified")
+}
+
+func TestRedactor_RedactJSON(t *testing.T) {
+	redactor := New(map[string]Policy{"access_token": Drop}).With(map[string]Policy{"scope": Hash})
+
+	got := redactor.RedactJSON([]byte(`{"access_token":"x3Lyfe","token_type":"Bearer","scope":"resource.READ"}`))
+	assert.JSONEq(t, `{"access_token":"[REDACTED]","token_type":"Bearer","scope":"`+HashString("resource.READ")+`"}`, string(got))
+
+	html := []byte("<html>Request Rejected</html>")
+	assert.Equal(t, html, redactor.RedactJSON(html))
+}
+
+func TestMaskString(t *testing.T) {
+	assert.Equal(t, "1119", MaskString("1119"))
+	assert.Equal(t, "*1119", MaskString("71119"))
+	assert.Equal(t, "", MaskString(""))
+}
@@ -0,0 +1,40 @@
+package bni
+
+import (
+	"net/url"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/redact"
+)
+
+// defaultRedactor applies the `redact` tags of every request and response DTO.
+func defaultRedactor() *redact.Redactor {
+	return redact.New(redact.FromStructTags(
+		dto.GetTokenResponse{},
+		dto.ApiResponse{},
+		dto.GetBalanceRequest{},
+		dto.GetInHouseInquiryRequest{},
+		dto.DoPaymentRequest{},
+		dto.GetPaymentStatusRequest{},
+		dto.GetInterBankInquiryRequest{},
+		dto.GetInterBankPaymentRequest{},
+	))
+}
+
+// redactURLError drops the query, which holds the access token, from the URL
+// of a transport error before it is logged or recorded on a span.
+func redactURLError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+
+	redacted := *urlErr
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		u.RawQuery = ""
+		redacted.URL = u.String()
+	} else {
+		redacted.URL = "<unparsable URL>"
+	}
+	return &redacted
+}
@@ -0,0 +1,158 @@
+package bni
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+var (
+	ErrNoTenant      = errors.New("no tenant in context")
+	ErrUnknownTenant = errors.New("unknown tenant")
+	ErrTenantExists  = errors.New("tenant already registered")
+)
+
+// Registry manages one BNI instance per tenant, each with its own config,
+// access token and signing key. Operations called on the Registry are routed
+// to the tenant set with context.WithTenantID.
+type Registry struct {
+	mutex   sync.RWMutex
+	clients map[string]*BNI
+	opts    []Option
+}
+
+// NewRegistry returns an empty Registry, opts are applied to every tenant before its own options.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{
+		clients: map[string]*BNI{},
+		opts:    opts,
+	}
+}
+
+// Add creates the BNI instance of tenantID. Unlike New it rejects a config
+// with an invalid LogLevel. Tenants logging to the same LogPath share its
+// file writer.
+func (r *Registry) Add(tenantID string, config config.Config, opts ...Option) (*BNI, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exist := r.clients[tenantID]; exist {
+		return nil, errors.Annotate(ErrTenantExists, tenantID)
+	}
+
+	allOpts := append(append([]Option{}, r.opts...), opts...)
+	client, err := newBNI(config, allOpts...)
+	if err != nil {
+		return nil, errors.Annotate(err, tenantID)
+	}
+	r.clients[tenantID] = client
+
+	return client, nil
+}
+
+// Remove forgets tenantID, calls already routed to it are not interrupted.
+func (r *Registry) Remove(tenantID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, exist := r.clients[tenantID]
+	delete(r.clients, tenantID)
+	return exist
+}
+
+// Get returns the BNI instance of tenantID.
+func (r *Registry) Get(tenantID string) (*BNI, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	client, exist := r.clients[tenantID]
+	if !exist {
+		return nil, errors.Annotate(ErrUnknownTenant, tenantID)
+	}
+	return client, nil
+}
+
+// Tenants returns the registered tenant IDs, sorted.
+func (r *Registry) Tenants() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tenantIDs := make([]string, 0, len(r.clients))
+	for tenantID := range r.clients {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Strings(tenantIDs)
+	return tenantIDs
+}
+
+// FromContext returns the BNI instance of the tenant set with context.WithTenantID.
+func (r *Registry) FromContext(ctx context.Context) (*BNI, error) {
+	tenantID := bniCtx.TenantID(ctx)
+	if tenantID == "" {
+		return nil, ErrNoTenant
+	}
+	return r.Get(tenantID)
+}
+
+// === APi routed by tenant ===
+
+func (r *Registry) DoAuthentication(ctx context.Context) (*dto.GetTokenResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.DoAuthentication(ctx)
+}
+
+func (r *Registry) GetBalance(ctx context.Context, dtoReq *dto.GetBalanceRequest) (*dto.GetBalanceResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.GetBalance(ctx, dtoReq)
+}
+
+func (r *Registry) GetInHouseInquiry(ctx context.Context, dtoReq *dto.GetInHouseInquiryRequest) (*dto.GetInHouseInquiryResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.GetInHouseInquiry(ctx, dtoReq)
+}
+
+func (r *Registry) DoPayment(ctx context.Context, dtoReq *dto.DoPaymentRequest) (*dto.DoPaymentResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.DoPayment(ctx, dtoReq)
+}
+
+func (r *Registry) GetPaymentStatus(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.GetPaymentStatus(ctx, dtoReq)
+}
+
+func (r *Registry) GetInterBankInquiry(ctx context.Context, dtoReq *dto.GetInterBankInquiryRequest) (*dto.GetInterBankInquiryResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.GetInterBankInquiry(ctx, dtoReq)
+}
+
+func (r *Registry) GetInterBankPayment(ctx context.Context, dtoReq *dto.GetInterBankPaymentRequest) (*dto.GetInterBankPaymentResponse, error) {
+	client, err := r.FromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return client.GetInterBankPayment(ctx, dtoReq)
+}
@@ -0,0 +1,64 @@
+package bni
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	bniCtx "github.com/fundex-id/bni-api-mgmt/context"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	clientIDs := make(chan string, 2)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var dtoReq dto.GetBalanceRequest
+		util.AssertErrNil(t, decodeJSONBody(req, &dtoReq))
+		clientIDs <- dtoReq.ClientID
+
+		_, err := w.Write(getJSON("testdata/get_balance_response.json"))
+		util.AssertErrNil(t, err)
+	}))
+	defer testServer.Close()
+
+	registry := NewRegistry()
+	for _, tenantID := range []string{"merchant-a", "merchant-b"} {
+		client, err := registry.Add(tenantID, config.Config{
+			ClientID:        "IDBNI-" + tenantID,
+			BNIServer:       testServer.URL,
+			SignatureConfig: dummySignatureConfig,
+		})
+		util.AssertErrNil(t, err)
+		client.api.httpClient = testServer.Client()
+	}
+
+	_, err := registry.Add("merchant-a", config.Config{})
+	assert.Equal(t, ErrTenantExists, errors.Cause(err))
+	_, err = registry.Add("merchant-c", config.Config{LogPath: testLogPath, LogLevel: "verbose"})
+	assert.True(t, errors.IsNotValid(err))
+	assert.Equal(t, []string{"merchant-a", "merchant-b"}, registry.Tenants())
+
+	ctx := bniCtx.WithTenantID(context.Background(), "merchant-b")
+	_, err = registry.GetBalance(ctx, &dto.GetBalanceRequest{AccountNo: "115471119"})
+	util.AssertErrNil(t, err)
+	assert.Equal(t, "IDBNI-merchant-b", <-clientIDs)
+
+	_, err = registry.GetBalance(context.Background(), &dto.GetBalanceRequest{})
+	assert.Equal(t, ErrNoTenant, errors.Cause(err))
+
+	assert.True(t, registry.Remove("merchant-b"))
+	_, err = registry.GetBalance(ctx, &dto.GetBalanceRequest{})
+	assert.Equal(t, ErrUnknownTenant, errors.Cause(err))
+
+	clientA, err := registry.Get("merchant-a")
+	util.AssertErrNil(t, err)
+	clientB, err := registry.Add("merchant-b", config.Config{})
+	util.AssertErrNil(t, err)
+	assert.NotSame(t, clientA.api, clientB.api)
+	assert.NotSame(t, clientA.signature, clientB.signature)
+}
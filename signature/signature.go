@@ -9,14 +9,20 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"io/ioutil"
+	"sync"
 
 	"github.com/fundex-id/bni-api-mgmt/config"
 	"github.com/juju/errors"
 )
 
+// Signature signs with the key of its config, read on first use. It is safe
+// for concurrent use.
 type Signature struct {
-	config     config.SignatureConfig
+	config config.SignatureConfig
+
+	loadOnce   sync.Once
 	privateKey *rsa.PrivateKey
+	loadErr    error
 }
 
 func New(config config.SignatureConfig) *Signature {
@@ -24,34 +30,27 @@ func New(config config.SignatureConfig) *Signature {
 }
 
 func (s *Signature) Sha256WithRSA(data string) (string, error) {
-
-	if s.privateKey == nil {
-		privateKey, err := loadPrivateKeyFromPEMFile(s.config.PrivateKeyPath)
-		if err != nil {
-			return "", errors.Trace(err)
-		}
-		s.privateKey = privateKey
+	s.loadOnce.Do(func() {
+		s.privateKey, s.loadErr = loadPrivateKeyFromPEMFile(s.config.PrivateKeyPath)
+	})
+	if s.loadErr != nil {
+		return "", errors.Trace(s.loadErr)
 	}
 
-	h := sha256.New()
-	_, err := h.Write([]byte(data))
-	if err != nil {
-		return "", errors.Trace(err)
-	}
-	d := h.Sum(nil)
+	return SignWithKey(s.privateKey, data)
+}
+
+// SignWithKey signs the SHA-256 of data with PKCS#1 v1.5 and base64 encodes it,
+// the format BNI expects in the signature field.
+func SignWithKey(privateKey *rsa.PrivateKey, data string) (string, error) {
+	d := sha256.Sum256([]byte(data))
 
-	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, d)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, d[:])
 	if err != nil {
 		return "", errors.Trace(err)
 	}
 
-	// logger.Infof("Signature in byte: %v\n\n", signature)
-
-	encodedSig := base64.StdEncoding.EncodeToString(signature)
-
-	// logger.Infof("Encoded signature: %v\n\n", encodedSig)
-
-	return encodedSig, nil
+	return base64.StdEncoding.EncodeToString(signature), nil
 }
 
 func loadPrivateKeyFromPEMFile(privKeyFileLocation string) (*rsa.PrivateKey, error) {
@@ -72,3 +71,85 @@ func loadPrivateKeyFromPEMFile(privKeyFileLocation string) (*rsa.PrivateKey, err
 
 	return privateKey, nil
 }
+
+// LoadPrivateKey reads a PKCS#1 "RSA PRIVATE KEY" PEM file.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	return loadPrivateKeyFromPEMFile(path)
+}
+
+// ParsePublicKeyPEM accepts either a PKIX "PUBLIC KEY" or a PKCS#1 "RSA PUBLIC KEY" block.
+func ParsePublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("Failed to load a valid public key")
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		return publicKey, errors.Trace(err)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not RSA")
+		}
+		return publicKey, nil
+	}
+
+	return nil, errors.Errorf("unsupported public key type %q", block.Type)
+}
+
+// LoadPublicKey reads a public key PEM file, see ParsePublicKeyPEM.
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	fileData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return ParsePublicKeyPEM(fileData)
+}
+
+// Verify checks a base64 signature produced by Sha256WithRSA over data.
+func Verify(publicKey *rsa.PublicKey, data, encodedSig string) error {
+	sig, err := base64.StdEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return errors.Annotate(err, "decode signature")
+	}
+
+	d := sha256.Sum256([]byte(data))
+	return errors.Trace(rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, d[:], sig))
+}
+
+// MinKeyBits is the smallest key size BNI accepts.
+const MinKeyBits = 2048
+
+// GenerateKey creates a keypair for BNI, bits must be at least MinKeyBits.
+func GenerateKey(bits int) (*rsa.PrivateKey, error) {
+	if bits < MinKeyBits {
+		return nil, errors.NotValidf("key size %d bits, BNI needs at least %d", bits, MinKeyBits)
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	return privateKey, errors.Trace(err)
+}
+
+// EncodePrivateKeyPEM encodes privateKey the way LoadPrivateKey reads it, PKCS#1 "RSA PRIVATE KEY".
+func EncodePrivateKeyPEM(privateKey *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+}
+
+// EncodePublicKeyPEM encodes publicKey as a PKIX "PUBLIC KEY", the format registered with BNI.
+func EncodePublicKeyPEM(publicKey *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
@@ -0,0 +1,320 @@
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+const (
+	DefaultInitialInterval = 5 * time.Second
+	DefaultMaxInterval     = 5 * time.Minute
+	DefaultDeadline        = time.Hour
+)
+
+// ErrNotTracked is returned for a CRN the poller does not track.
+var ErrNotTracked = errors.New("payment not tracked")
+
+// Transition is a change of state of a tracked payment. Response is the
+// status answer that caused it, nil for the move to Unknown at the deadline.
+type Transition struct {
+	CRN      string
+	From     State
+	To       State
+	Response *dto.GetPaymentStatusResponse
+	At       time.Time
+}
+
+type PollerOption func(*Poller)
+
+// WithBackoff sets the wait before the first status check of a payment,
+// doubled after each check that does not settle it, up to max.
+func WithBackoff(initial, max time.Duration) PollerOption {
+	return func(p *Poller) {
+		if initial > 0 {
+			p.initial = initial
+		}
+		if max >= p.initial {
+			p.max = max
+		}
+	}
+}
+
+// WithDeadline sets how long after Track a payment is polled before it is
+// given up as Unknown.
+func WithDeadline(d time.Duration) PollerOption {
+	return func(p *Poller) {
+		if d > 0 {
+			p.deadline = d
+		}
+	}
+}
+
+func WithClock(now func() time.Time) PollerOption {
+	return func(p *Poller) {
+		p.now = now
+	}
+}
+
+// Poller checks the status of tracked payments until each settles or reaches
+// its deadline, and tells subscribers of every state change. A payment leaves
+// the poller once Success or Failed, or as Unknown at its deadline.
+type Poller struct {
+	client   bni.Client
+	initial  time.Duration
+	max      time.Duration
+	deadline time.Duration
+	now      func() time.Time
+
+	mutex       sync.Mutex
+	tracked     map[string]*tracked
+	subscribers map[int]func(Transition)
+	nextID      int
+	wake        chan struct{}
+}
+
+type tracked struct {
+	state    State
+	interval time.Duration
+	due      time.Time
+	deadline time.Time
+}
+
+func NewPoller(client bni.Client, opts ...PollerOption) *Poller {
+	p := &Poller{
+		client:      client,
+		initial:     DefaultInitialInterval,
+		max:         DefaultMaxInterval,
+		deadline:    DefaultDeadline,
+		now:         time.Now,
+		tracked:     map[string]*tracked{},
+		subscribers: map[int]func(Transition){},
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Track starts polling crn, as Initiated. Tracking a CRN again restarts its
+// backoff and deadline but keeps its state.
+func (p *Poller) Track(crn string) {
+	p.mutex.Lock()
+	now := p.now()
+	state := Initiated
+	if existing, ok := p.tracked[crn]; ok {
+		state = existing.state
+	}
+	p.tracked[crn] = &tracked{
+		state:    state,
+		interval: p.initial,
+		due:      now.Add(p.initial),
+		deadline: now.Add(p.deadline),
+	}
+	p.mutex.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Untrack stops polling crn without a transition.
+func (p *Poller) Untrack(crn string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.tracked, crn)
+}
+
+// State returns the state of a tracked payment.
+func (p *Poller) State(crn string) (State, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	entry, ok := p.tracked[crn]
+	if !ok {
+		return "", errors.Annotate(ErrNotTracked, crn)
+	}
+	return entry.state, nil
+}
+
+// Outstanding returns how many payments are being polled.
+func (p *Poller) Outstanding() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.tracked)
+}
+
+// Subscribe calls fn on every transition, from the Run goroutine, until the
+// returned function is called. Run waits for fn, so it should return quickly.
+func (p *Poller) Subscribe(fn func(Transition)) (unsubscribe func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	id := p.nextID
+	p.nextID++
+	p.subscribers[id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			delete(p.subscribers, id)
+		})
+	}
+}
+
+// Channel delivers transitions on a channel with room for buffer of them.
+// Run blocks while the channel is full, so keep reading until unsubscribe,
+// which closes it.
+func (p *Poller) Channel(buffer int) (<-chan Transition, func()) {
+	ch := make(chan Transition, buffer)
+	done := make(chan struct{})
+	var mutex sync.Mutex
+
+	unsubscribe := p.Subscribe(func(t Transition) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		select {
+		case ch <- t:
+		case <-done:
+		}
+	})
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			unsubscribe()
+			close(done)
+			// wait for a send in progress before closing
+			mutex.Lock()
+			defer mutex.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Run polls until ctx is done, which is the error it returns.
+func (p *Poller) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		for _, crn := range p.due() {
+			p.poll(ctx, crn)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(p.untilNext())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// due returns the payments to check now.
+func (p *Poller) due() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.now()
+	var crns []string
+	for crn, entry := range p.tracked {
+		if !entry.due.After(now) {
+			crns = append(crns, crn)
+		}
+	}
+	return crns
+}
+
+// untilNext is the wait until the next payment is due, an hour when none is tracked.
+func (p *Poller) untilNext() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	wait := time.Hour
+	now := p.now()
+	for _, entry := range p.tracked {
+		if d := entry.due.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// poll checks one payment. An error answer from BNI, e.g. a payment it has
+// not recorded yet, or a failed request leaves the state as it is.
+func (p *Poller) poll(ctx context.Context, crn string) {
+	resp, err := p.client.GetPaymentStatus(ctx, &dto.GetPaymentStatusRequest{CustomerReferenceNumber: crn})
+	if ctx.Err() != nil {
+		return
+	}
+
+	p.mutex.Lock()
+	entry, ok := p.tracked[crn]
+	if !ok {
+		// untracked while the request was out
+		p.mutex.Unlock()
+		return
+	}
+
+	now := p.now()
+	from := entry.state
+	to := from
+	if err == nil {
+		to = FromResponse(resp)
+	}
+	expired := !to.Terminal() && !now.Before(entry.deadline)
+	if expired {
+		to, resp = Unknown, nil
+	}
+	if !from.CanTransition(to) {
+		// e.g. an Unknown payment back to Initiated, keep what we know
+		to = from
+	}
+
+	entry.state = to
+	if to.Terminal() || expired {
+		delete(p.tracked, crn)
+	} else {
+		entry.interval *= 2
+		if entry.interval > p.max {
+			entry.interval = p.max
+		}
+		entry.due = now.Add(entry.interval)
+		if entry.due.After(entry.deadline) {
+			entry.due = entry.deadline
+		}
+	}
+
+	var subscribers []func(Transition)
+	if to != from {
+		for _, fn := range p.subscribers {
+			subscribers = append(subscribers, fn)
+		}
+	}
+	p.mutex.Unlock()
+
+	transition := Transition{CRN: crn, From: from, To: to, Response: resp, At: now}
+	for _, fn := range subscribers {
+		fn(transition)
+	}
+}
@@ -0,0 +1,90 @@
+// Package status turns GetPaymentStatus answers into a typed payment state
+// and polls outstanding payments until BNI settles them.
+//
+//	poller := status.NewPoller(client, status.WithDeadline(30*time.Minute))
+//	poller.Subscribe(func(t status.Transition) { log.Println(t.CRN, t.From, "->", t.To) })
+//	poller.Track(crn)
+//	go poller.Run(ctx)
+package status
+
+import (
+	"fmt"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+)
+
+// State is where a payment stands, as far as we know.
+type State string
+
+const (
+	// Initiated means the payment was sent and BNI has not reported on it yet.
+	Initiated State = "INITIATED"
+	// Pending means BNI knows the payment but has not settled it.
+	Pending State = "PENDING"
+	// Success means BNI made the payment.
+	Success State = "SUCCESS"
+	// Failed means BNI did not make the payment.
+	Failed State = "FAILED"
+	// Unknown means polling gave up before BNI settled the payment, check it by hand.
+	Unknown State = "UNKNOWN"
+)
+
+// Transaction statuses of GetPaymentStatus.
+const (
+	transactionSucceeded = "Y"
+	transactionFailed    = "N"
+)
+
+// ErrInvalidTransition is returned by Transition for a move the state machine forbids.
+var ErrInvalidTransition = errors.New("invalid payment state transition")
+
+// transitions lists the states each state may move to. Unknown is not final:
+// a later status check, or reconciliation, can still settle the payment.
+var transitions = map[State][]State{
+	Initiated: {Pending, Success, Failed, Unknown},
+	Pending:   {Success, Failed, Unknown},
+	Unknown:   {Pending, Success, Failed},
+}
+
+// Terminal tells whether the state is final, Success or Failed.
+func (s State) Terminal() bool {
+	return s == Success || s == Failed
+}
+
+// CanTransition tells whether a payment may move from s to next. Staying in
+// the same state is always allowed.
+func (s State) CanTransition(next State) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition returns next, or ErrInvalidTransition when s cannot move to it.
+func (s State) Transition(next State) (State, error) {
+	if !s.CanTransition(next) {
+		return s, errors.Annotate(ErrInvalidTransition, fmt.Sprintf("%s to %s", s, next))
+	}
+	return next, nil
+}
+
+// FromResponse reads the state of a payment from its GetPaymentStatus
+// answer: "Y" is Success, "N" is Failed and any other value Pending.
+func FromResponse(resp *dto.GetPaymentStatusResponse) State {
+	if resp == nil {
+		return Initiated
+	}
+	switch resp.Parameters.PreviousResponse.TransactionStatus {
+	case transactionSucceeded:
+		return Success
+	case transactionFailed:
+		return Failed
+	}
+	return Pending
+}
@@ -0,0 +1,118 @@
+package status
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bni "github.com/fundex-id/bni-api-mgmt"
+	"github.com/fundex-id/bni-api-mgmt/bnifake"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func statusResponse(transactionStatus string) *dto.GetPaymentStatusResponse {
+	resp := &dto.GetPaymentStatusResponse{}
+	resp.Parameters.ResponseCode = "0001"
+	resp.Parameters.PreviousResponse.TransactionStatus = transactionStatus
+	return resp
+}
+
+func TestFromResponse(t *testing.T) {
+	assert.Equal(t, Success, FromResponse(statusResponse("Y")))
+	assert.Equal(t, Failed, FromResponse(statusResponse("N")))
+	assert.Equal(t, Pending, FromResponse(statusResponse("")))
+	assert.Equal(t, Initiated, FromResponse(nil))
+}
+
+func TestState_Transition(t *testing.T) {
+	next, err := Initiated.Transition(Pending)
+	assert.Nil(t, err)
+	assert.Equal(t, Pending, next)
+
+	assert.True(t, Unknown.CanTransition(Success))
+	assert.True(t, Success.CanTransition(Success))
+	assert.False(t, Pending.CanTransition(Initiated))
+
+	next, err = Success.Transition(Failed)
+	assert.Equal(t, ErrInvalidTransition, errors.Cause(err))
+	assert.Equal(t, Success, next)
+
+	assert.True(t, Failed.Terminal())
+	assert.False(t, Unknown.Terminal())
+}
+
+func waitFor(t *testing.T, ch <-chan Transition) Transition {
+	select {
+	case transition := <-ch:
+		return transition
+	case <-time.After(5 * time.Second):
+		t.Fatal("no transition")
+		return Transition{}
+	}
+}
+
+func TestPoller(t *testing.T) {
+	fake := bnifake.New().
+		QueueGetPaymentStatus(nil, bni.BadResponseError).
+		QueueGetPaymentStatus(statusResponse("P"), nil).
+		QueueGetPaymentStatus(nil, errors.New("connection reset")).
+		QueueGetPaymentStatus(statusResponse("Y"), nil)
+
+	poller := NewPoller(fake, WithBackoff(time.Millisecond, 4*time.Millisecond))
+	var called int32
+	unsubscribe := poller.Subscribe(func(Transition) { atomic.AddInt32(&called, 1) })
+	defer unsubscribe()
+	ch, stop := poller.Channel(2)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go poller.Run(ctx)
+
+	poller.Track("20170227000000000020")
+	state, err := poller.State("20170227000000000020")
+	assert.Nil(t, err)
+	assert.Equal(t, Initiated, state)
+
+	pending := waitFor(t, ch)
+	assert.Equal(t, Initiated, pending.From)
+	assert.Equal(t, Pending, pending.To)
+
+	success := waitFor(t, ch)
+	assert.Equal(t, Pending, success.From)
+	assert.Equal(t, Success, success.To)
+	assert.Equal(t, "Y", success.Response.Parameters.PreviousResponse.TransactionStatus)
+
+	cancel()
+	assert.Len(t, fake.CallsTo(bni.GetPaymentStatusOperation), 4)
+	assert.Equal(t, 0, poller.Outstanding())
+	_, err = poller.State("20170227000000000020")
+	assert.Equal(t, ErrNotTracked, errors.Cause(err))
+	// the channel may get the last transition before the callback does
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&called) == 2 }, time.Second, time.Millisecond)
+}
+
+func TestPoller_Deadline(t *testing.T) {
+	fake := bnifake.New()
+	fake.GetPaymentStatusFunc = func(ctx context.Context, dtoReq *dto.GetPaymentStatusRequest) (*dto.GetPaymentStatusResponse, error) {
+		return statusResponse("P"), nil
+	}
+
+	poller := NewPoller(fake, WithBackoff(time.Millisecond, 2*time.Millisecond), WithDeadline(20*time.Millisecond))
+	ch, stop := poller.Channel(2)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go poller.Run(ctx)
+	poller.Track("20170227000000000020")
+
+	assert.Equal(t, Pending, waitFor(t, ch).To)
+	unknown := waitFor(t, ch)
+	assert.Equal(t, Unknown, unknown.To)
+	assert.Nil(t, unknown.Response)
+	assert.Equal(t, 0, poller.Outstanding())
+}
@@ -0,0 +1,86 @@
+package bni
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/redact"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/fundex-id/bni-api-mgmt"
+
+const (
+	operationAttr       = attribute.Key("bni.operation")
+	accountNoAttr       = attribute.Key("bni.account_no")
+	debitAccountNoAttr  = attribute.Key("bni.debit_account_no")
+	creditAccountNoAttr = attribute.Key("bni.credit_account_no")
+	destAccountNumAttr  = attribute.Key("bni.destination_account_num")
+	destBankCodeAttr    = attribute.Key("bni.destination_bank_code")
+	crnAttr             = attribute.Key("bni.customer_reference_number")
+	responseCodeAttr    = attribute.Key("bni.response_code")
+	httpMethodAttr      = attribute.Key("http.method")
+	httpPathAttr        = attribute.Key("http.target")
+	httpStatusCodeAttr  = attribute.Key("http.status_code")
+)
+
+func defaultTracer() trace.Tracer {
+	return trace.NewNoopTracerProvider().Tracer(tracerName)
+}
+
+// startSpan starts a child span of whatever span ctx carries.
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err (if any) on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTraceContext propagates the span in ctx to BNI using the global propagator.
+func injectTraceContext(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// requestAttributes describes a request DTO without leaking full account numbers.
+func requestAttributes(dtoReq interface{}) []attribute.KeyValue {
+	switch req := dtoReq.(type) {
+	case *dto.GetBalanceRequest:
+		return []attribute.KeyValue{accountNoAttr.String(redact.MaskString(req.AccountNo))}
+	case *dto.GetInHouseInquiryRequest:
+		return []attribute.KeyValue{accountNoAttr.String(redact.MaskString(req.AccountNo))}
+	case *dto.DoPaymentRequest:
+		return []attribute.KeyValue{
+			crnAttr.String(req.CustomerReferenceNumber),
+			debitAccountNoAttr.String(redact.MaskString(req.DebitAccountNo)),
+			creditAccountNoAttr.String(redact.MaskString(req.CreditAccountNo)),
+		}
+	case *dto.GetPaymentStatusRequest:
+		return []attribute.KeyValue{crnAttr.String(req.CustomerReferenceNumber)}
+	case *dto.GetInterBankInquiryRequest:
+		return []attribute.KeyValue{
+			crnAttr.String(req.CustomerReferenceNumber),
+			accountNoAttr.String(redact.MaskString(req.AccountNum)),
+			destAccountNumAttr.String(redact.MaskString(req.DestinationAccountNum)),
+			destBankCodeAttr.String(req.DestinationBankCode),
+		}
+	case *dto.GetInterBankPaymentRequest:
+		return []attribute.KeyValue{
+			crnAttr.String(req.CustomerReferenceNumber),
+			accountNoAttr.String(redact.MaskString(req.AccountNum)),
+			destAccountNumAttr.String(redact.MaskString(req.DestinationAccountNum)),
+			destBankCodeAttr.String(req.DestinationBankCode),
+		}
+	}
+	return nil
+}
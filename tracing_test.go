@@ -0,0 +1,59 @@
+package bni
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fundex-id/bni-api-mgmt/config"
+	"github.com/fundex-id/bni-api-mgmt/dto"
+	"github.com/fundex-id/bni-api-mgmt/util"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBNI_WithTracerProvider(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	bni, testServer := buildBNIAndMockServerGoodResponse(t, config.Config{
+		LogPath:         testLogPath,
+		SignatureConfig: dummySignatureConfig,
+	}, InHouseTransferPath, "testdata/get_dopayment_response.json", WithTracerProvider(tracerProvider))
+	defer testServer.Close()
+
+	parentCtx, parent := tracerProvider.Tracer("test").Start(context.Background(), "caller")
+	_, err := bni.DoPayment(parentCtx, &dto.DoPaymentRequest{
+		CustomerReferenceNumber: "20170227000000000020",
+		DebitAccountNo:          "113183203",
+		CreditAccountNo:         "115471119",
+		ValueAmount:             "100500",
+		ValueCurrency:           "IDR",
+	})
+	parent.End()
+	util.AssertErrNil(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spanRecorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	root := spans["BNI "+DoPaymentOperation]
+	if assert.NotNil(t, root) {
+		assert.Equal(t, parent.SpanContext().SpanID(), root.Parent().SpanID())
+
+		attrs := map[string]string{}
+		for _, attr := range root.Attributes() {
+			attrs[string(attr.Key)] = attr.Value.Emit()
+		}
+		assert.Equal(t, "20170227000000000020", attrs["bni.customer_reference_number"])
+		assert.Equal(t, "*****3203", attrs["bni.debit_account_no"])
+		assert.Equal(t, "0001", attrs["bni.response_code"])
+	}
+
+	for _, name := range []string{"BNI signature", "HTTP POST"} {
+		if assert.Contains(t, spans, name) {
+			assert.Equal(t, root.SpanContext().SpanID(), spans[name].Parent().SpanID())
+		}
+	}
+}